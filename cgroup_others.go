@@ -0,0 +1,32 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var errCgroupUnsupported = errors.New("running smartctl in a cgroup is only supported on Linux")
+
+func setCgroup(cmd *exec.Cmd, path string) (*os.File, error) {
+	return nil, errCgroupUnsupported
+}
+
+func validateCgroup(path string) error {
+	return errCgroupUnsupported
+}
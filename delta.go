@@ -0,0 +1,126 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// prometheusLookbackDelta is the default Prometheus query lookback
+const prometheusLookbackDelta = 5 * time.Minute
+
+// deltaSeries is the last exposed state of a series
+type deltaSeries struct {
+	value   float64
+	exposed time.Time
+}
+
+// deltaGatherer omits gauges and counters whose value did not change since
+// they were last exposed, for at most refresh. Exposed samples carry an
+// explicit timestamp, which exempts the omitted series from Prometheus
+// staleness handling until they fall out of the query lookback.
+type deltaGatherer struct {
+	gatherer prometheus.Gatherer
+	refresh  time.Duration
+	now      func() time.Time
+
+	mutex  sync.Mutex
+	series map[string]deltaSeries
+}
+
+// newDeltaGatherer wraps the gatherer, if refresh is positive
+func newDeltaGatherer(gatherer prometheus.Gatherer, refresh time.Duration) prometheus.Gatherer {
+	if refresh <= 0 {
+		return gatherer
+	}
+	return &deltaGatherer{
+		gatherer: gatherer,
+		refresh:  refresh,
+		now:      time.Now,
+		series:   map[string]deltaSeries{},
+	}
+}
+
+// deltaValue returns the value of gauges, counters and untyped metrics
+func deltaValue(metric *dto.Metric) (float64, bool) {
+	switch {
+	case metric.Gauge != nil:
+		return metric.Gauge.GetValue(), true
+	case metric.Counter != nil:
+		return metric.Counter.GetValue(), true
+	case metric.Untyped != nil:
+		return metric.Untyped.GetValue(), true
+	}
+	return 0, false
+}
+
+func deltaKey(name string, metric *dto.Metric) string {
+	var key strings.Builder
+	key.WriteString(name)
+	for _, pair := range metric.Label {
+		key.WriteString("\xff")
+		key.WriteString(pair.GetName())
+		key.WriteString("=")
+		key.WriteString(pair.GetValue())
+	}
+	return key.String()
+}
+
+// Gather implements prometheus.Gatherer
+func (g *deltaGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	now := g.now()
+	timestamp := now.UnixMilli()
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	seen := map[string]bool{}
+	var result []*dto.MetricFamily
+	for _, family := range families {
+		var metrics []*dto.Metric
+		for _, metric := range family.Metric {
+			value, ok := deltaValue(metric)
+			if !ok {
+				metrics = append(metrics, metric)
+				continue
+			}
+			key := deltaKey(family.GetName(), metric)
+			seen[key] = true
+			last, known := g.series[key]
+			if known && last.value == value && now.Sub(last.exposed) < g.refresh {
+				continue
+			}
+			g.series[key] = deltaSeries{value: value, exposed: now}
+			metric.TimestampMs = &timestamp
+			metrics = append(metrics, metric)
+		}
+		if len(metrics) > 0 {
+			family.Metric = metrics
+			result = append(result, family)
+		}
+	}
+	// Forget series no longer collected, so they are exposed again when they
+	// reappear
+	for key := range g.series {
+		if !seen[key] {
+			delete(g.series, key)
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestDeltaGatherer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_gauge"}, []string{"device"})
+	gauge.WithLabelValues("sda").Set(1)
+	gauge.WithLabelValues("sdb").Set(1)
+	reg.MustRegister(gauge)
+
+	now := time.Unix(1000, 0)
+	gatherer := newDeltaGatherer(reg, time.Minute).(*deltaGatherer)
+	gatherer.now = func() time.Time { return now }
+	exposed := func() []string {
+		t.Helper()
+		families, err := gatherer.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var devices []string
+		for _, family := range families {
+			for _, metric := range family.Metric {
+				if metric.GetTimestampMs() != now.UnixMilli() {
+					t.Errorf("timestamp = %d, want %d", metric.GetTimestampMs(), now.UnixMilli())
+				}
+				devices = append(devices, metric.Label[0].GetValue())
+			}
+		}
+		return devices
+	}
+
+	if devices := exposed(); len(devices) != 2 {
+		t.Fatalf("first scrape exposed %v", devices)
+	}
+	now = now.Add(15 * time.Second)
+	if devices := exposed(); len(devices) != 0 {
+		t.Fatalf("unchanged scrape exposed %v", devices)
+	}
+	gauge.WithLabelValues("sdb").Set(2)
+	now = now.Add(15 * time.Second)
+	if devices := exposed(); len(devices) != 1 || devices[0] != "sdb" {
+		t.Fatalf("changed scrape exposed %v", devices)
+	}
+	now = now.Add(45 * time.Second)
+	if devices := exposed(); len(devices) != 1 || devices[0] != "sda" {
+		t.Fatalf("refresh scrape exposed %v", devices)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// fixtureCollector collects the metrics of a single captured smartctl json
+type fixtureCollector struct {
+	logger log.Logger
+	json   gjson.Result
+}
+
+// Describe sends the super-set of all possible descriptors of metrics
+func (c fixtureCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+// Collect is called by the Prometheus registry when collecting metrics.
+func (c fixtureCollector) Collect(ch chan<- prometheus.Metric) {
+	smart := NewSMARTctl(c.logger, c.json, ch)
+	smart.Collect()
+}
+
+// collectFixture generates the metrics for a captured smartctl json file and
+// returns the number of metrics produced
+func collectFixture(logger log.Logger, filename string) (int, error) {
+	json := readFakeSMARTctlFile(logger, filename)
+	if !json.Exists() || len(json.Map()) == 0 {
+		return 0, fmt.Errorf("no valid json data")
+	}
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(fixtureCollector{logger: logger, json: json}); err != nil {
+		return 0, err
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, family := range families {
+		count += len(family.GetMetric())
+	}
+	return count, nil
+}
+
+// validateFixtures generates the metrics for every json file in dir and
+// returns the files that failed or produced no metrics
+func validateFixtures(logger log.Logger, dir string) (map[string]error, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no json fixtures found in %s", dir)
+	}
+	failed := map[string]error{}
+	for _, filename := range filenames {
+		count, err := collectFixture(logger, filename)
+		if err == nil && count == 0 {
+			err = fmt.Errorf("no metrics produced")
+		}
+		if err != nil {
+			failed[filename] = err
+			continue
+		}
+		level.Debug(logger).Log("msg", "Validated fixture", "filename", filename, "metrics", count)
+	}
+	return failed, nil
+}
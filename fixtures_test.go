@@ -0,0 +1,37 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestValidateFixtures(t *testing.T) {
+	groups, err := parseCollectGroups(strings.Join(metricGroups, ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collectGroups = groups
+
+	failed, err := validateFixtures(log.NewNopLogger(), "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for filename, err := range failed {
+		t.Errorf("fixture %s: %v", filename, err)
+	}
+}
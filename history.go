@@ -0,0 +1,106 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// pollOutcome is the result of a single smartctl run
+type pollOutcome struct {
+	Timestamp  time.Time `json:"timestamp"`
+	ExitStatus int64     `json:"exit_status"`
+	Duration   float64   `json:"duration_seconds"`
+	Success    bool      `json:"success"`
+}
+
+// pollHistory is a ring buffer of the latest poll outcomes of a device
+type pollHistory struct {
+	mutex    sync.Mutex
+	outcomes []pollOutcome
+	next     int
+}
+
+// pollHistories holds the *pollHistory of every device, next to jsonCache
+var pollHistories sync.Map
+
+func (h *pollHistory) add(outcome pollOutcome, size int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if len(h.outcomes) < size {
+		h.outcomes = append(h.outcomes, outcome)
+		return
+	}
+	h.outcomes[h.next] = outcome
+	h.next = (h.next + 1) % len(h.outcomes)
+}
+
+// list returns the outcomes, oldest first
+func (h *pollHistory) list() []pollOutcome {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append(append([]pollOutcome{}, h.outcomes[h.next:]...), h.outcomes[:h.next]...)
+}
+
+// recordPoll adds the outcome of a smartctl run to the history of the device
+func recordPoll(device Device, start time.Time, exitStatus int64, success bool) {
+	if *smartctlPollHistory <= 0 {
+		return
+	}
+	value, _ := pollHistories.LoadOrStore(device, &pollHistory{})
+	value.(*pollHistory).add(pollOutcome{
+		Timestamp:  start,
+		ExitStatus: exitStatus,
+		Duration:   time.Since(start).Seconds(),
+		Success:    success,
+	}, *smartctlPollHistory)
+}
+
+// historyHandler serves the poll history of the device given by the device
+// query parameter, or of all devices, as json by device name and type, e.g.
+// /dev/sda;sat
+func historyHandler(logger log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("device")
+		histories := map[string][]pollOutcome{}
+		pollHistories.Range(func(key, value any) bool {
+			device := key.(Device)
+			if name == "" || name == device.Name || name == device.Info_Name {
+				key := device.Name + ";" + device.Type
+				histories[key] = append(histories[key], value.(*pollHistory).list()...)
+			}
+			return true
+		})
+		for _, outcomes := range histories {
+			slices.SortStableFunc(outcomes, func(a, b pollOutcome) int {
+				return a.Timestamp.Compare(b.Timestamp)
+			})
+		}
+		if name != "" && len(histories) == 0 {
+			http.Error(w, "No poll history for device "+name, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(histories); err != nil {
+			level.Warn(logger).Log("msg", "Writing poll history failed", "err", err)
+		}
+	}
+}
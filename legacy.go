@@ -0,0 +1,64 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// legacyMetricNames maps renamed metrics to their previous name, which they
+// are exported under as well with smartctl.emit-legacy-names. An entry is
+// removed two minor releases after the rename.
+var legacyMetricNames = map[string]string{}
+
+// legacyNameGatherer exports the gathered metrics with a legacy name a second
+// time under that name
+type legacyNameGatherer struct {
+	gatherer prometheus.Gatherer
+	names    map[string]string
+}
+
+// newLegacyNameGatherer wraps the gatherer, if there are any legacy names
+func newLegacyNameGatherer(gatherer prometheus.Gatherer, names map[string]string) prometheus.Gatherer {
+	if len(names) == 0 {
+		return gatherer
+	}
+	return legacyNameGatherer{gatherer: gatherer, names: names}
+}
+
+// Gather implements prometheus.Gatherer
+func (g legacyNameGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	result := families
+	for _, family := range families {
+		legacy, ok := g.names[family.GetName()]
+		if !ok {
+			continue
+		}
+		// The samples are shared, both families expose the same values
+		result = append(result, &dto.MetricFamily{
+			Name:   stringPtr(legacy),
+			Help:   stringPtr("Deprecated, renamed to " + family.GetName() + ". " + family.GetHelp()),
+			Type:   family.Type,
+			Metric: family.Metric,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetName() < result[j].GetName()
+	})
+	return result, err
+}
@@ -0,0 +1,42 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLegacyNameGatherer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_new_name", Help: "Test"})
+	gauge.Set(3)
+	reg.MustRegister(gauge, prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_other"}))
+
+	families, err := newLegacyNameGatherer(reg, map[string]string{"test_new_name": "test_old_name"}).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+		if family.GetName() == "test_old_name" && family.Metric[0].GetGauge().GetValue() != 3 {
+			t.Errorf("test_old_name = %v, want 3", family.Metric[0].GetGauge().GetValue())
+		}
+	}
+	if len(names) != 3 || names[0] != "test_new_name" || names[1] != "test_old_name" || names[2] != "test_other" {
+		t.Errorf("got families %v", names)
+	}
+}
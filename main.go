@@ -14,10 +14,16 @@
 package main
 
 import (
+	"fmt"
+	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
@@ -26,6 +32,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/common/promlog/flag"
 	"github.com/prometheus/common/version"
@@ -46,34 +53,296 @@ type SMARTctlManagerCollector struct {
 	CollectPeriodDuration time.Duration
 	Devices               []Device
 
+	// Devices found by a rescan, by the time of their first poll
+	firstPoll map[Device]time.Time
+	// Devices are not polled before, after smartctl --scan touched them
+	pollAfter time.Time
+
 	logger log.Logger
 	mutex  sync.Mutex
 }
 
 // Describe sends the super-set of all possible descriptors of metrics
 func (i *SMARTctlManagerCollector) Describe(ch chan<- *prometheus.Desc) {
-	prometheus.DescribeByCollect(i, ch)
+	for _, desc := range metricDescs {
+		ch <- desc
+	}
+	for _, vendorLog := range vendorLogs {
+		for _, field := range vendorLog.fields {
+			ch <- field.desc
+		}
+	}
+	jsonParseInvalid.Describe(ch)
+	jsonOutputOversized.Describe(ch)
+	ch <- devicesSkippedFresh.Desc()
+	ch <- devicesNeededTypeFallback.Desc()
+	ch <- duplicateDevices.Desc()
 }
 
-// Collect is called by the Prometheus registry when collecting metrics.
+// Collect is called by the Prometheus registry when collecting metrics. The
+// metrics of every device are sent as soon as it is read, so that the
+// registry processes them while the next devices are read, and the manager is
+// only locked to take a snapshot of the devices.
 func (i *SMARTctlManagerCollector) Collect(ch chan<- prometheus.Metric) {
 	info := NewSMARTctlInfo(ch)
 	i.mutex.Lock()
-	for _, device := range i.Devices {
-		json := readData(i.logger, device)
-		if json.Exists() {
-			info.SetJSON(json)
-			smart := NewSMARTctl(i.logger, json, ch)
-			smart.Collect()
+	devices := i.Devices
+	interval := effectiveInterval(len(devices))
+	cachedOnly := time.Now().Before(i.pollAfter)
+	warming := make([]bool, len(devices))
+	newlyDiscovered := make([]float64, len(devices))
+	for n, device := range devices {
+		warming[n], newlyDiscovered[n] = i.discoveryState(device, interval)
+	}
+	i.mutex.Unlock()
+
+	failing, warning := 0, 0
+	for n, device := range devices {
+		switch i.collectDevice(ch, &info, device, interval, cachedOnly, warming[n], newlyDiscovered[n]) {
+		case healthFailing:
+			failing++
+		case healthWarning:
+			warning++
 		}
 	}
 	ch <- prometheus.MustNewConstMetric(
 		metricDeviceCount,
 		prometheus.GaugeValue,
-		float64(len(i.Devices)),
+		float64(len(devices)),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		metricDevicesFailing,
+		prometheus.GaugeValue,
+		float64(failing),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		metricDevicesWarning,
+		prometheus.GaugeValue,
+		float64(warning),
+	)
+	paused := 0.0
+	if pollingPaused.Load() {
+		paused = 1
+	}
+	ch <- prometheus.MustNewConstMetric(
+		metricPollingPaused,
+		prometheus.GaugeValue,
+		paused,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		metricEffectivePollInterval,
+		prometheus.GaugeValue,
+		interval.Seconds(),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		metricSmartctlResolveDuration,
+		prometheus.GaugeValue,
+		smartctlResolveDuration.Seconds(),
 	)
 	info.Collect()
-	i.mutex.Unlock()
+	jsonParseInvalid.Collect(ch)
+	jsonOutputOversized.Collect(ch)
+	ch <- devicesSkippedFresh
+	ch <- devicesNeededTypeFallback
+	ch <- duplicateDevices
+}
+
+// collectDevice sends the metrics of the device and returns its health
+func (i *SMARTctlManagerCollector) collectDevice(ch chan<- prometheus.Metric, info *SMARTctlInfo, device Device, interval time.Duration, cachedOnly bool, warming bool, newlyDiscovered float64) int {
+	label, alias := deviceLabel(device), lookupAlias(device.Name, device.Info_Name)
+	// Minimal mode exports nothing per device but the health and
+	// temperatures
+	if !*smartctlMinimal {
+		ch <- prometheus.MustNewConstMetric(
+			metricDeviceNewlyDiscovered,
+			prometheus.GaugeValue,
+			newlyDiscovered,
+			label,
+			alias,
+			device.Type,
+		)
+		counters := countersOf(device)
+		ch <- prometheus.MustNewConstMetric(
+			metricDevicePollRetries,
+			prometheus.CounterValue,
+			float64(counters.pollRetries.Load()),
+			label,
+			alias,
+			device.Type,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			metricDeviceEmptyOutput,
+			prometheus.CounterValue,
+			float64(counters.emptyOutputs.Load()),
+			label,
+			alias,
+			device.Type,
+		)
+	}
+	if warming {
+		level.Debug(i.logger).Log("msg", "Delaying first poll of newly discovered device", "device", device.Info_Name)
+		return healthUnknown
+	}
+	var data JSONCache
+	if cachedOnly {
+		// Serve the cached data only, the scan just accessed the device
+		cached, ok := jsonCache.Load(device)
+		if !ok {
+			return healthUnknown
+		}
+		data = cached.(JSONCache)
+	} else {
+		data = pollData(i.logger, device, interval)
+		// The poll may have brought the identity of a new device
+		label = deviceLabel(device)
+	}
+	if accessible, ok := deviceAccess.Load(device); ok && !*smartctlMinimal {
+		value := 0.0
+		if accessible.(bool) {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(
+			metricDeviceAccessible,
+			prometheus.GaugeValue,
+			value,
+			label,
+			alias,
+			device.Type,
+		)
+	}
+	if !*smartctlMinimal {
+		ch <- prometheus.MustNewConstMetric(
+			metricDevicePollInterval,
+			prometheus.GaugeValue,
+			pollInterval(device, interval).Seconds(),
+			label,
+			alias,
+			device.Type,
+		)
+	}
+	if data.JSON.Exists() {
+		// smartd states carry no smartctl version
+		if device.Type != smartdDeviceType {
+			info.SetJSON(data.JSON)
+		}
+		smart := NewSMARTctlFromCache(i.logger, data, ch)
+		smart.Collect()
+	}
+	if *smartctlMinimal {
+		return deviceHealth(data.JSON)
+	}
+	// Command line errors leave no device data in the json, so
+	// report them for the configured device.
+	ch <- prometheus.MustNewConstMetric(
+		metricDeviceArgParseError,
+		prometheus.GaugeValue,
+		float64(data.ExitStatus&exitCommandLineError),
+		label,
+		alias,
+		device.Type,
+	)
+	return deviceHealth(data.JSON)
+}
+
+// scrapeCollector records the duration and number of the collections of the
+// wrapped collector. It is only registered for the scrapes, the pushes
+// collect the same devices without counting as one.
+type scrapeCollector struct {
+	collector      *SMARTctlManagerCollector
+	scrapeDuration prometheus.Histogram
+	scrapes        prometheus.Counter
+}
+
+func newScrapeCollector(collector *SMARTctlManagerCollector) *scrapeCollector {
+	return &scrapeCollector{
+		collector: collector,
+		scrapeDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "smartctl_scrape_duration_seconds",
+			Help:    "Duration of a full collection of all devices",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+		}),
+		scrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "smartctl_scrapes_total",
+			Help: "Total number of collections of all devices",
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *scrapeCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.collector.Describe(ch)
+	ch <- c.scrapeDuration.Desc()
+	ch <- c.scrapes.Desc()
+}
+
+// Collect implements prometheus.Collector
+func (c *scrapeCollector) Collect(ch chan<- prometheus.Metric) {
+	start := time.Now()
+	c.collector.Collect(ch)
+	c.scrapeDuration.Observe(time.Since(start).Seconds())
+	c.scrapes.Inc()
+	ch <- c.scrapeDuration
+	ch <- c.scrapes
+}
+
+// effectiveInterval returns the interval between smartctl polls of a device,
+// stretched to keep the total poll rate within the configured maximum
+func effectiveInterval(deviceCount int) time.Duration {
+	interval := *smartctlInterval
+	if *smartctlAdaptiveInterval && *smartctlMaxPollRate > 0 {
+		minimum := time.Duration(float64(deviceCount) / *smartctlMaxPollRate * float64(time.Second))
+		if minimum > interval {
+			interval = minimum
+		}
+	}
+	return interval
+}
+
+// markNewDevices records the devices not known before and delays their first
+// poll by a random warmup, to spread the load of mass hot-plug events
+func (i *SMARTctlManagerCollector) markNewDevices(devices []Device) {
+	if i.firstPoll == nil {
+		i.firstPoll = map[Device]time.Time{}
+	}
+	now := time.Now()
+	for _, device := range devices {
+		if slices.Contains(i.Devices, device) {
+			continue
+		}
+		var warmup time.Duration
+		if *smartctlWarmupDelay > 0 {
+			warmup = time.Duration(rand.Int63n(int64(*smartctlWarmupDelay)))
+		}
+		level.Info(i.logger).Log("msg", "Discovered new device", "name", device.Info_Name, "warmup", warmup)
+		i.firstPoll[device] = now.Add(warmup)
+	}
+}
+
+// discoveryState returns whether a device found by a rescan is still warming
+// up and whether it is within its first interval since the first poll
+func (i *SMARTctlManagerCollector) discoveryState(device Device, interval time.Duration) (bool, float64) {
+	firstPoll, ok := i.firstPoll[device]
+	if !ok {
+		return false, 0
+	}
+	now := time.Now()
+	if now.Before(firstPoll) {
+		return true, 1
+	}
+	if now.Before(firstPoll.Add(interval)) {
+		return false, 1
+	}
+	delete(i.firstPoll, device)
+	return false, 0
+}
+
+// delayPolls holds off polling devices for the scan poll delay after a
+// smartctl --scan
+func (i *SMARTctlManagerCollector) delayPolls() {
+	if *smartctlScanPollDelay <= 0 || *smartctlNoScan || *smartctlSmartdStateDir != "" || *smartctlFakeData {
+		return
+	}
+	i.pollAfter = time.Now().Add(*smartctlScanPollDelay)
 }
 
 func (i *SMARTctlManagerCollector) RescanForDevices() {
@@ -81,8 +350,12 @@ func (i *SMARTctlManagerCollector) RescanForDevices() {
 		time.Sleep(*smartctlRescanInterval)
 		level.Info(i.logger).Log("msg", "Rescanning for devices")
 		devices := scanDevices(i.logger)
+		refreshDeviceNumbers(devices)
 		i.mutex.Lock()
+		i.markNewDevices(devices)
 		i.Devices = devices
+		syncDeviceState(devices)
+		i.delayPolls()
 		i.mutex.Unlock()
 	}
 }
@@ -91,15 +364,105 @@ var (
 	smartctlPath = kingpin.Flag("smartctl.path",
 		"The path to the smartctl binary",
 	).Default("/usr/sbin/smartctl").String()
+	smartctlCommandWrapper = kingpin.Flag("smartctl.command-wrapper",
+		"Command template smartctl is run with, e.g. to enter the host namespaces: nsenter --target 1 --mount -- {smartctl} {args}. Quoted words are kept together, {smartctl} is required and replaced by smartctl.path and {args} by the smartctl arguments, which are appended if {args} is absent",
+	).Default("").String()
+	smartctlEnv = kingpin.Flag("smartctl.env",
+		"Environment variable set for smartctl in the form KEY=VALUE, e.g. for vendor plugins (repeatable)",
+	).Strings()
+	smartctlCleanEnv = kingpin.Flag("smartctl.clean-env",
+		"Run smartctl with only the variables given by smartctl.env instead of the inherited environment",
+	).Default("false").Bool()
+	smartctlTimeout = kingpin.Flag("smartctl.timeout",
+		"Maximum time a single smartctl invocation may run before it and its children are killed. 0 disables the timeout",
+	).Default("0s").Duration()
+	smartctlRetries = kingpin.Flag("smartctl.retries",
+		"Number of times a failed smartctl poll of a device is retried",
+	).Default("0").Int()
+	smartctlRetryDelay = kingpin.Flag("smartctl.retry-delay",
+		"Delay before retrying a failed smartctl poll",
+	).Default("1s").Duration()
+	smartctlIgnoreExitBits = kingpin.Flag("smartctl.ignore-exit-bits",
+		"Comma separated list of smartctl exit status bits treated as success when deciding whether to cache a poll, e.g. 6,7",
+	).Default("").String()
+	smartctlPollHistory = kingpin.Flag("smartctl.poll-history",
+		"Number of recent poll outcomes kept per device for /debug/history. 0 disables the history",
+	).Default("10").Int()
+	smartctlPlaintextFallback = kingpin.Flag("smartctl.plaintext-fallback",
+		"Parse health, temperature, power on hours and attributes from the plaintext output of smartctl versions before 7.0, which lack json output",
+	).Default("false").Bool()
+	smartctlMaxOutputBytes = kingpin.Flag("smartctl.max-output-bytes",
+		"Maximum size of smartctl output to accept, larger outputs are rejected. 0 disables the limit",
+	).Default("0").Int64()
+	smartctlNice = kingpin.Flag("smartctl.nice",
+		"Niceness adjustment to run smartctl with via nice(1). 0 disables the adjustment",
+	).Default("0").Int()
+	smartctlIoniceClass = kingpin.Flag("smartctl.ionice-class",
+		"IO scheduling class to run smartctl with via ionice(1). One of: [idle, best-effort, realtime]. Empty disables",
+	).Default("").Enum("", "idle", "best-effort", "realtime")
+	smartctlCgroup = kingpin.Flag("smartctl.cgroup",
+		"Path of a cgroup v2 directory to start smartctl processes in (Linux only). Empty disables",
+	).Default("").String()
 	smartctlInterval = kingpin.Flag("smartctl.interval",
 		"The interval between smartctl polls",
 	).Default("60s").Duration()
+	smartctlMinInterval = kingpin.Flag("smartctl.min-interval",
+		"Minimum interval between smartctl polls of rotational devices, longer intervals let them spin down. 0 disables the floor",
+	).Default("0s").Duration()
+	smartctlAdaptiveInterval = kingpin.Flag("smartctl.adaptive-interval",
+		"Stretch the interval between smartctl polls when needed to keep the total poll rate within smartctl.max-poll-rate",
+	).Default("false").Bool()
+	smartctlMaxPollRate = kingpin.Flag("smartctl.max-poll-rate",
+		"Maximum number of smartctl polls per second across all devices when smartctl.adaptive-interval is enabled",
+	).Default("1").Float64()
+	smartctlNeverWake = kingpin.Flag("smartctl.never-wake",
+		"Do not run smartctl against a device for this long after it was found in a low-power mode. 0 disables the guard",
+	).Default("0s").Duration()
+	smartctlExcludeNoSMART = kingpin.Flag("smartctl.exclude-no-smart",
+		"Do not poll devices again once they were found not to support SMART, e.g. loop and virtual devices, until the exporter restarts",
+	).Default("false").Bool()
+	smartctlSmartdStateDir = kingpin.Flag("smartctl.smartd-state-dir",
+		"Read the attributes smartd stores in its state files in this directory instead of running smartctl, e.g. /var/lib/smartmontools",
+	).Default("").String()
+	smartctlStartupProbe = kingpin.Flag("smartctl.startup-probe",
+		"Open every device with smartctl --info at startup, logging devices that cannot be accessed and why",
+	).Default("true").Bool()
 	smartctlRescanInterval = kingpin.Flag("smartctl.rescan",
 		"The interval between rescanning for new/disappeared devices. If the interval is smaller than 1s no rescanning takes place. If any devices are configured with smartctl.device also no rescanning takes place.",
 	).Default("10m").Duration()
+	smartctlWarmupDelay = kingpin.Flag("smartctl.warmup-delay",
+		"Maximum random delay before the first poll of devices discovered by a rescan",
+	).Default("10s").Duration()
+	smartctlScanPollDelay = kingpin.Flag("smartctl.scan-poll-delay",
+		"Delay between a smartctl --scan and polling the devices, so they are not accessed back to back. Cached data is served meanwhile",
+	).Default("0s").Duration()
 	smartctlDevices = kingpin.Flag("smartctl.device",
 		"The device to monitor (repeatable)",
 	).Strings()
+	smartctlNoScan = kingpin.Flag("smartctl.no-scan",
+		"Do not run smartctl --scan, only monitor the devices given by smartctl.device, as full paths with the type probed like the auto device type, and smartctl.device-type",
+	).Default("false").Bool()
+	smartctlDeviceTypes = kingpin.Flag("smartctl.device-type",
+		"Additionally monitor a device with the given smartctl device type, in the form DEVICE=TYPE (repeatable). The type auto probes sat, scsi, nvme and ata in this order",
+	).Strings()
+	smartctlDeviceAliases = kingpin.Flag("smartctl.device-alias",
+		"Alias for a device in the form DEVICE=ALIAS, exported as the alias label of its metrics (repeatable)",
+	).Strings()
+	smartctlScanNvme = kingpin.Flag("smartctl.scan-nvme",
+		"Additionally discover NVMe devices in /dev, for systems where smartctl's scan does not list them",
+	).Default("false").Bool()
+	smartctlNvmeControllerOnly = kingpin.Flag("smartctl.nvme-controller-only",
+		"Monitor NVMe controllers instead of each of their namespaces, as the health data is controller wide",
+	).Default("false").Bool()
+	smartctlNvmeNamespaceMetrics = kingpin.Flag("smartctl.nvme-namespace-metrics",
+		"Export the utilization of every namespace reported by NVMe controllers",
+	).Default("false").Bool()
+	smartctlSkipRemovable = kingpin.Flag("smartctl.skip-removable",
+		"Do not monitor removable media such as USB sticks and card readers",
+	).Default("false").Bool()
+	smartctlOmitDevicePath = kingpin.Flag("smartctl.omit-device-path",
+		"Use the WWN or serial number of devices as device label instead of their path, which is exported in smartctl_device_path_info",
+	).Default("false").Bool()
 	smartctlDeviceExclude = kingpin.Flag(
 		"smartctl.device-exclude",
 		"Regexp of devices to exclude from automatic scanning. (mutually exclusive to device-include)",
@@ -108,6 +471,60 @@ var (
 		"smartctl.device-include",
 		"Regexp of devices to exclude from automatic scanning. (mutually exclusive to device-exclude)",
 	).Default("").String()
+	smartctlCollect = kingpin.Flag("smartctl.collect",
+		"Comma separated list of metric groups to collect. Any of: ["+strings.Join(metricGroups, ", ")+"]. The xerror group reads the extended ATA error log and is not collected by default",
+	).Default(strings.Join(defaultMetricGroups, ",")).String()
+	smartctlMinimal = kingpin.Flag("smartctl.minimal",
+		"Only read the health status and attributes and export smartctl_device_smart_status and smartctl_device_temperature per device, for fast polls of many devices. Overrides smartctl.collect, smartctl.collect-log-directory, smartctl.vendor-logs and the device type specific arguments",
+	).Default("false").Bool()
+	smartctlAttributeRawString = kingpin.Flag("smartctl.attribute-raw-string",
+		"Comma separated list of ATA SMART attribute IDs whose raw string is exported in smartctl_device_attribute_raw_string, e.g. 9,194",
+	).Default("").String()
+	smartctlCriticalAttributes = kingpin.Flag("smartctl.critical-attributes",
+		"Comma separated list of ATA SMART attribute IDs smartctl_device_health_score is derived from. Empty disables the score",
+	).Default("5,187,197,198").String()
+	smartctlCollectLogDirectory = kingpin.Flag("smartctl.collect-log-directory",
+		"Comma separated list of hexadecimal ATA log addresses whose sizes are exported from the log directory, e.g. 0x04,0x30",
+	).Default("").String()
+	smartctlVendorLogs = kingpin.Flag("smartctl.vendor-logs",
+		"Request vendor specific logs such as the Seagate FARM log (requires smartmontools >= 7.4)",
+	).Default("false").Bool()
+	smartctlLabels = kingpin.Flag("smartctl.label",
+		"Static label added to every smartctl metric in the form NAME=VALUE, e.g. datacenter=dc1 (repeatable)",
+	).Strings()
+	smartctlEmitLegacyNames = kingpin.Flag("smartctl.emit-legacy-names",
+		"Export renamed metrics under their previous name as well, until the previous name is removed two minor releases after the rename",
+	).Default("false").Bool()
+	smartctlRelabelConfig = kingpin.Flag("smartctl.relabel-config",
+		"Path to a yaml file with relabel_configs applied to the labels of the exported metrics. Supports the replace, keep, drop, labeldrop, labelkeep and labelmap actions",
+	).Default("").String()
+	smartctlDeltaExposition = kingpin.Flag("smartctl.experimental.delta-exposition",
+		"[EXPERIMENTAL] Omit series whose value did not change since they were last exposed, for at most the given duration. Exposed samples carry timestamps. Must stay below the Prometheus lookback delta and supports a single scraper only. 0 disables",
+	).Default("0s").Duration()
+	smartctlPushGateway = kingpin.Flag("smartctl.push-gateway",
+		"URL of a Pushgateway to push metrics to after every smartctl interval. Empty disables pushing",
+	).Default("").String()
+	smartctlPushJob = kingpin.Flag("smartctl.push-job",
+		"Job label used when pushing metrics to the Pushgateway",
+	).Default("smartctl_exporter").String()
+	otlpEndpoint = kingpin.Flag("otlp.endpoint",
+		"OTLP/HTTP metrics endpoint, e.g. http://localhost:4318/v1/metrics, to push metrics to in the JSON encoding after every smartctl interval. Empty disables pushing",
+	).Default("").String()
+	smartctlCacheSnapshot = kingpin.Flag("smartctl.cache-snapshot",
+		"Path of a file the cached smartctl json is written to every smartctl.interval and restored from at startup, so devices polled within their interval are not polled again after a restart. Empty disables the snapshot",
+	).Default("").String()
+	smartctlDumpJSONDir = kingpin.Flag("smartctl.dump-json-dir",
+		"Directory the smartctl json of every device is written to after successful polls, named like the files read with smartctl.fake-data. Empty disables dumping",
+	).Default("").String()
+	smartctlDumpJSONInterval = kingpin.Flag("smartctl.dump-json-interval",
+		"Minimum interval between writes of the smartctl json of a device to smartctl.dump-json-dir",
+	).Default("10m").Duration()
+	smartctlValidateFixtures = kingpin.Flag("smartctl.validate-fixtures",
+		"Generate metrics for every smartctl json file in the given directory, report those producing no metrics and exit",
+	).Default("").Hidden().String()
+	smartctlFakeScenario = kingpin.Flag("smartctl.fake-scenario",
+		"Serve fake data of a scenario instead of polling devices, e.g. to test alerting rules. A directory of smartctl json files, one per device, or a built-in scenario: "+strings.Join(builtinScenarioNames(), ", "),
+	).Default("").String()
 	smartctlFakeData = kingpin.Flag("smartctl.fake-data",
 		"The device to monitor (repeatable)",
 	).Default("false").Hidden().Bool()
@@ -116,6 +533,24 @@ var (
 // scanDevices uses smartctl to gather the list of available devices.
 func scanDevices(logger log.Logger) []Device {
 	filter := newDeviceFilter(*smartctlDeviceExclude, *smartctlDeviceInclude)
+	devicesNeededTypeFallback.Set(0)
+
+	if *smartctlSmartdStateDir != "" {
+		return scanSmartdStates(logger, *smartctlSmartdStateDir, filter)
+	}
+
+	if fakeScenario != nil {
+		return scanScenarioDevices(logger, fakeScenario)
+	}
+
+	if *smartctlNoScan {
+		// The types of the listed devices are unknown without the scan
+		pairs := slices.Clone(*smartctlDeviceTypes)
+		for _, name := range *smartctlDevices {
+			pairs = append(pairs, name+"=auto")
+		}
+		return removeDuplicateDevices(logger, appendDeviceTypes(logger, nil, pairs))
+	}
 
 	json := readSMARTctlDevices(logger)
 	scanDevices := json.Get("devices").Array()
@@ -134,7 +569,184 @@ func scanDevices(logger log.Logger) []Device {
 			scanDeviceResult = append(scanDeviceResult, device)
 		}
 	}
-	return scanDeviceResult
+	if *smartctlScanNvme {
+		scanDeviceResult = appendNvmeDevices(logger, scanDeviceResult, filter)
+	}
+	if *smartctlNvmeControllerOnly {
+		scanDeviceResult = collapseNvmeNamespaces(logger, scanDeviceResult)
+	}
+	if *smartctlSkipRemovable {
+		scanDeviceResult = removeRemovableDevices(logger, scanDeviceResult)
+	}
+	return removeDuplicateDevices(logger, appendDeviceTypes(logger, scanDeviceResult, *smartctlDeviceTypes))
+}
+
+// removeDuplicateDevices keeps only the first of the devices resolving to
+// the same path with the same device type, e.g. a device added by its
+// /dev/disk/by-id link with smartctl.device-type that the scan found too
+func removeDuplicateDevices(logger log.Logger, devices []Device) []Device {
+	var result []Device
+	known := map[Device]Device{}
+	for _, device := range devices {
+		key := Device{Name: device.Name, Type: device.Type}
+		if path, err := filepath.EvalSymlinks(device.Name); err == nil {
+			key.Name = path
+		}
+		if first, found := known[key]; found {
+			if first.Name != device.Name {
+				level.Warn(logger).Log("msg", "Ignoring device registered twice under different names", "name", device.Name, "registered", first.Name, "type", device.Type)
+			} else {
+				level.Debug(logger).Log("msg", "Ignoring duplicate device", "name", device.Name, "type", device.Type)
+			}
+			continue
+		}
+		known[key] = device
+		result = append(result, device)
+	}
+	duplicateDevices.Set(float64(len(devices) - len(result)))
+	return result
+}
+
+var (
+	nvmeControllerRegexp = regexp.MustCompile(`^/dev/nvme[0-9]+$`)
+	nvmeNamespaceRegexp  = regexp.MustCompile(`^(/dev/nvme[0-9]+)n[0-9]+$`)
+)
+
+// appendNvmeDevices adds the NVMe controllers found in /dev that smartctl's
+// scan missed. Namespaces are only added when their controller is absent.
+func appendNvmeDevices(logger log.Logger, devices []Device, filter deviceFilter) []Device {
+	paths, err := filepath.Glob("/dev/nvme*")
+	if err != nil {
+		level.Warn(logger).Log("msg", "NVMe device discovery failed", "err", err)
+		return devices
+	}
+	known := map[string]bool{}
+	for _, device := range devices {
+		known[device.Name] = true
+	}
+	for _, path := range paths {
+		if !nvmeControllerRegexp.MatchString(path) || known[path] {
+			continue
+		}
+		known[path] = true
+		devices = appendNvmeDevice(logger, devices, filter, path)
+	}
+	for _, path := range paths {
+		match := nvmeNamespaceRegexp.FindStringSubmatch(path)
+		if match == nil || known[match[1]] || known[path] {
+			continue
+		}
+		known[path] = true
+		devices = appendNvmeDevice(logger, devices, filter, path)
+	}
+	return devices
+}
+
+func appendNvmeDevice(logger log.Logger, devices []Device, filter deviceFilter, path string) []Device {
+	deviceName := extractDiskName(path)
+	if filter.ignored(deviceName) {
+		level.Info(logger).Log("msg", "Ignoring device", "name", deviceName)
+		return devices
+	}
+	level.Info(logger).Log("msg", "Found NVMe device", "name", deviceName)
+	return append(devices, Device{
+		Name:      path,
+		Info_Name: deviceName,
+		Type:      "nvme",
+	})
+}
+
+// parseStaticLabels parses a list of NAME=VALUE pairs into labels added to
+// every metric of the collector
+func parseStaticLabels(pairs []string) (prometheus.Labels, error) {
+	labels := prometheus.Labels{}
+	for _, pair := range pairs {
+		name, value, found := strings.Cut(pair, "=")
+		if !found || !model.LabelName(name).IsValid() || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("expected NAME=VALUE with a valid label name, got %q", pair)
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
+
+// removeRemovableDevices removes USB attached devices and those flagged as
+// removable media in sysfs, e.g. card readers
+func removeRemovableDevices(logger log.Logger, devices []Device) []Device {
+	var result []Device
+	for _, device := range devices {
+		if strings.HasPrefix(device.Type, "usb") || deviceIsRemovable(device.Name) {
+			level.Info(logger).Log("msg", "Ignoring removable device", "name", device.Info_Name)
+			continue
+		}
+		result = append(result, device)
+	}
+	return result
+}
+
+// deviceIsRemovable reports whether the kernel flags the block device as
+// removable. Devices without sysfs entry are not.
+func deviceIsRemovable(name string) bool {
+	if path, err := filepath.EvalSymlinks(name); err == nil {
+		name = path
+	}
+	removable, err := os.ReadFile(filepath.Join("/sys/block", filepath.Base(name), "removable"))
+	return err == nil && strings.TrimSpace(string(removable)) == "1"
+}
+
+// collapseNvmeNamespaces replaces NVMe namespaces by their controller,
+// registering every controller only once
+func collapseNvmeNamespaces(logger log.Logger, devices []Device) []Device {
+	var result []Device
+	known := map[Device]bool{}
+	for _, device := range devices {
+		if match := nvmeNamespaceRegexp.FindStringSubmatch(device.Name); match != nil && device.Type == "nvme" {
+			level.Debug(logger).Log("msg", "Using NVMe controller instead of namespace", "namespace", device.Name, "controller", match[1])
+			device.Name = match[1]
+			device.Info_Name = extractDiskName(match[1])
+		}
+		if known[device] {
+			continue
+		}
+		known[device] = true
+		result = append(result, device)
+	}
+	return result
+}
+
+// appendDeviceTypes registers devices again with the additional device types
+// given as DEVICE=TYPE pairs
+func appendDeviceTypes(logger log.Logger, devices []Device, pairs []string) []Device {
+	for _, pair := range pairs {
+		name, deviceType, found := strings.Cut(pair, "=")
+		if !found || name == "" || deviceType == "" {
+			level.Warn(logger).Log("msg", "Ignoring invalid device type, expected DEVICE=TYPE", "device_type", pair)
+			continue
+		}
+		probed := deviceType == "auto"
+		if probed {
+			probedType, ok := probeDeviceType(logger, name)
+			if !ok {
+				level.Warn(logger).Log("msg", "No device type yields SMART data, ignoring device", "name", name, "candidates", strings.Join(deviceTypeCandidates, ","))
+				continue
+			}
+			deviceType = probedType
+		}
+		device := Device{
+			Name:      name,
+			Info_Name: extractDiskName(name),
+			Type:      deviceType,
+		}
+		if slices.Contains(devices, device) {
+			continue
+		}
+		if probed {
+			devicesNeededTypeFallback.Inc()
+		}
+		level.Info(logger).Log("msg", "Adding device type", "name", device.Info_Name, "type", deviceType)
+		devices = append(devices, device)
+	}
+	return devices
 }
 
 func filterDevices(logger log.Logger, devices []Device, filters []string) []Device {
@@ -151,10 +763,75 @@ func filterDevices(logger log.Logger, devices []Device, filters []string) []Devi
 	return filtered
 }
 
+// limitRequests serves at most max requests concurrently. As many more wait
+// up to wait for a slot, further requests are rejected right away.
+func limitRequests(logger log.Logger, handler http.Handler, max int, wait time.Duration) http.Handler {
+	if max <= 0 {
+		return handler
+	}
+	slots := make(chan struct{}, max)
+	queue := make(chan struct{}, max)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case queue <- struct{}{}:
+		default:
+			level.Warn(logger).Log("msg", "Rejecting metrics request, too many requests waiting", "remote", r.RemoteAddr)
+			http.Error(w, "Too many concurrent requests", http.StatusServiceUnavailable)
+			return
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case slots <- struct{}{}:
+			<-queue
+		case <-timer.C:
+			<-queue
+			level.Warn(logger).Log("msg", "Rejecting metrics request, timed out waiting", "remote", r.RemoteAddr, "wait", wait)
+			http.Error(w, "Too many concurrent requests", http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			<-queue
+			return
+		}
+		defer func() { <-slots }()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// pollingPaused stops polling devices, serving the cached data instead
+var pollingPaused atomic.Bool
+
+// pollingHandler pauses or resumes polling devices on POST requests
+func pollingHandler(logger log.Logger, pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if pollingPaused.Swap(pause) != pause {
+			level.Info(logger).Log("msg", "Polling devices", "paused", pause)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
 func main() {
 	metricsPath := kingpin.Flag(
 		"web.telemetry-path", "Path under which to expose metrics",
 	).Default("/metrics").String()
+	enableDebugEndpoints := kingpin.Flag(
+		"web.enable-debug-endpoints", "Enable the /-/pause and /-/resume endpoints to pause and resume polling devices, and /debug/history serving the recent poll outcomes",
+	).Default("false").Bool()
+	maxRequests := kingpin.Flag(
+		"web.max-requests", "Maximum number of concurrent metrics requests, as many more wait up to web.max-requests-wait and any further are rejected with 503. 0 disables the limit",
+	).Default("0").Int()
+	maxRequestsWait := kingpin.Flag(
+		"web.max-requests-wait", "Maximum time a metrics request waits for one of web.max-requests to finish before it is rejected with 503",
+	).Default("10s").Duration()
+	idleTimeout := kingpin.Flag(
+		"web.idle-timeout", "Maximum time to keep idle keep-alive connections open, 0 keeps them open until the read timeout",
+	).Default("0s").Duration()
 	toolkitFlags := webflag.AddFlags(kingpin.CommandLine, ":9633")
 
 	promlogConfig := &promlog.Config{}
@@ -167,35 +844,210 @@ func main() {
 	level.Info(logger).Log("msg", "Starting smartctl_exporter", "version", version.Info())
 	level.Info(logger).Log("msg", "Build context", "build_context", version.BuildContext())
 
+	if *smartctlMinInterval > *smartctlInterval {
+		level.Warn(logger).Log("msg", "Interval is below the minimum interval, it will be clamped for rotational devices", "interval", *smartctlInterval, "min_interval", *smartctlMinInterval)
+	}
+
+	groups, err := parseCollectGroups(*smartctlCollect)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid metric groups", "err", err)
+		os.Exit(1)
+	}
+	collectGroups = groups
+	if *smartctlMinimal {
+		level.Info(logger).Log("msg", "Minimal mode, only the health status and temperatures are collected")
+	}
+
+	attributeIDs, err := parseAttributeIDs(*smartctlAttributeRawString)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid attribute IDs", "err", err)
+		os.Exit(1)
+	}
+	rawStringAttributes = attributeIDs
+
+	criticalIDs, err := parseAttributeIDs(*smartctlCriticalAttributes)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid critical attribute IDs", "err", err)
+		os.Exit(1)
+	}
+	criticalAttributes = criticalIDs
+
+	addresses, err := parseLogAddresses(*smartctlCollectLogDirectory)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid log directory addresses", "err", err)
+		os.Exit(1)
+	}
+	logDirectoryAddresses = addresses
+
+	aliases, err := parseDeviceAliases(*smartctlDeviceAliases)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid device aliases", "err", err)
+		os.Exit(1)
+	}
+	deviceAliases = aliases
+
+	if *smartctlValidateFixtures != "" {
+		failed, err := validateFixtures(logger, *smartctlValidateFixtures)
+		if err != nil {
+			level.Error(logger).Log("msg", "Fixture validation failed", "err", err)
+			os.Exit(1)
+		}
+		for filename, err := range failed {
+			level.Error(logger).Log("msg", "Invalid fixture", "filename", filename, "err", err)
+		}
+		if len(failed) > 0 {
+			os.Exit(1)
+		}
+		level.Info(logger).Log("msg", "All fixtures produced metrics", "dir", *smartctlValidateFixtures)
+		os.Exit(0)
+	}
+
+	exitBits, err := parseExitBits(*smartctlIgnoreExitBits)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid exit status bits", "err", err)
+		os.Exit(1)
+	}
+	ignoredExitBits = exitBits
+
+	wrapper, err := parseCommandWrapper(*smartctlCommandWrapper)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid command wrapper", "err", err)
+		os.Exit(1)
+	}
+	commandWrapper = wrapper
+
+	env, err := parseCommandEnv(*smartctlEnv, *smartctlCleanEnv)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid smartctl environment", "err", err)
+		os.Exit(1)
+	}
+	commandEnv = env
+
+	if err := validateCommandWrappers(); err != nil {
+		level.Error(logger).Log("msg", "Invalid smartctl command configuration", "err", err)
+		os.Exit(1)
+	}
+
+	if *smartctlFakeScenario != "" {
+		scenario, err := loadFakeScenario(*smartctlFakeScenario)
+		if err != nil {
+			level.Error(logger).Log("msg", "Invalid fake data scenario", "err", err)
+			os.Exit(1)
+		}
+		level.Info(logger).Log("msg", "Serving fake data scenario", "scenario", *smartctlFakeScenario)
+		fakeScenario = scenario
+		*smartctlFakeData = true
+	}
+
+	if !*smartctlFakeData {
+		resolveSMARTctl(logger)
+	}
+
+	if *smartctlPlaintextFallback && !*smartctlFakeData && !smartctlSupportsJSON(logger) {
+		level.Warn(logger).Log("msg", "smartctl does not support json output, falling back to parsing a subset of its plaintext output")
+		plaintextMode = true
+	}
+
+	if *smartctlNoScan && len(*smartctlDevices) == 0 && len(*smartctlDeviceTypes) == 0 {
+		level.Error(logger).Log("msg", "No devices to monitor, smartctl.no-scan requires smartctl.device or smartctl.device-type")
+		os.Exit(1)
+	}
+
+	if *smartctlCacheSnapshot != "" {
+		if err := loadCacheSnapshot(logger, *smartctlCacheSnapshot); err != nil {
+			level.Warn(logger).Log("msg", "Restoring the cache snapshot failed", "file", *smartctlCacheSnapshot, "err", err)
+		}
+		go saveCacheSnapshots(logger, *smartctlCacheSnapshot, *smartctlInterval)
+	}
+
 	var devices []Device
 	devices = scanDevices(logger)
 	level.Info(logger).Log("msg", "Number of devices found", "count", len(devices))
-	if len(*smartctlDevices) > 0 {
+	if len(*smartctlDevices) > 0 && !*smartctlNoScan {
 		level.Info(logger).Log("msg", "Devices specified", "devices", strings.Join(*smartctlDevices, ", "))
 		devices = filterDevices(logger, devices, *smartctlDevices)
 		level.Info(logger).Log("msg", "Devices filtered", "count", len(devices))
 	}
+	refreshDeviceNumbers(devices)
+	syncDeviceState(devices)
+	if *smartctlStartupProbe && !*smartctlFakeData && *smartctlSmartdStateDir == "" {
+		probeDeviceAccess(logger, devices)
+	}
 
 	collector := SMARTctlManagerCollector{
 		Devices: devices,
 		logger:  logger,
 	}
+	collector.delayPolls()
 
-	if *smartctlRescanInterval >= 1*time.Second {
+	if *smartctlRescanInterval >= 1*time.Second && !*smartctlNoScan && fakeScenario == nil {
 		level.Info(logger).Log("msg", "Start background scan process")
 		level.Info(logger).Log("msg", "Rescanning for devices every", "rescanInterval", *smartctlRescanInterval)
 		go collector.RescanForDevices()
 	}
 
+	staticLabels, err := parseStaticLabels(*smartctlLabels)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid static labels", "err", err)
+		os.Exit(1)
+	}
+
+	var relabelRules []relabelRule
+	if *smartctlRelabelConfig != "" {
+		relabelRules, err = loadRelabelRules(*smartctlRelabelConfig)
+		if err != nil {
+			level.Error(logger).Log("msg", "Invalid relabel config", "file", *smartctlRelabelConfig, "err", err)
+			os.Exit(1)
+		}
+	}
+
+	var legacyNames map[string]string
+	if *smartctlEmitLegacyNames {
+		legacyNames = legacyMetricNames
+		level.Info(logger).Log("msg", "Exporting renamed metrics under their previous name as well", "metrics", len(legacyNames))
+	}
+
+	if *smartctlPushGateway != "" {
+		level.Info(logger).Log("msg", "Pushing metrics to Pushgateway", "url", *smartctlPushGateway, "interval", *smartctlInterval)
+		pushReg := prometheus.NewRegistry()
+		prometheus.WrapRegistererWith(staticLabels, pushReg).MustRegister(&collector)
+		go PushMetrics(logger, newLegacyNameGatherer(newRelabelGatherer(pushReg, relabelRules), legacyNames), *smartctlPushGateway, *smartctlPushJob, *smartctlInterval)
+	}
+
+	if *otlpEndpoint != "" {
+		level.Info(logger).Log("msg", "Pushing metrics to OTLP endpoint", "endpoint", *otlpEndpoint, "interval", *smartctlInterval)
+		otlpReg := prometheus.NewRegistry()
+		prometheus.WrapRegistererWith(staticLabels, otlpReg).MustRegister(&collector)
+		go PushOTLP(logger, newLegacyNameGatherer(newRelabelGatherer(otlpReg, relabelRules), legacyNames), *otlpEndpoint, *smartctlInterval)
+	}
+
 	reg := prometheus.NewPedanticRegistry()
 	reg.MustRegister(
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		collectors.NewGoCollector(),
 	)
 
-	prometheus.WrapRegistererWithPrefix("", reg).MustRegister(&collector)
+	if err := prometheus.WrapRegistererWith(staticLabels, reg).Register(newScrapeCollector(&collector)); err != nil {
+		level.Error(logger).Log("msg", "Registering the collector failed, check smartctl.label for conflicting label names", "err", err)
+		os.Exit(1)
+	}
+	prometheus.WrapRegistererWith(staticLabels, reg).MustRegister(devicesPolling)
 
-	http.Handle(*metricsPath, promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	if *smartctlDeltaExposition > 0 {
+		level.Warn(logger).Log("msg", "Delta exposition is experimental, unchanged series are omitted", "refresh", *smartctlDeltaExposition)
+		if *smartctlDeltaExposition >= prometheusLookbackDelta {
+			level.Warn(logger).Log("msg", "Delta exposition refresh reaches the default Prometheus lookback delta, omitted series will show gaps", "lookback_delta", prometheusLookbackDelta)
+		}
+	}
+
+	metricsHandler := promhttp.HandlerFor(newDeltaGatherer(newLegacyNameGatherer(newRelabelGatherer(reg, relabelRules), legacyNames), *smartctlDeltaExposition), promhttp.HandlerOpts{})
+	http.Handle(*metricsPath, limitRequests(logger, metricsHandler, *maxRequests, *maxRequestsWait))
+
+	if *enableDebugEndpoints {
+		http.Handle("/-/pause", pollingHandler(logger, true))
+		http.Handle("/-/resume", pollingHandler(logger, false))
+		http.Handle("/debug/history", historyHandler(logger))
+	}
 
 	if *metricsPath != "/" && *metricsPath != "" {
 		landingConfig := web.LandingConfig{
@@ -217,7 +1069,7 @@ func main() {
 		http.Handle("/", landingPage)
 	}
 
-	srv := &http.Server{}
+	srv := &http.Server{IdleTimeout: *idleTimeout}
 	if err := web.ListenAndServe(srv, toolkitFlags, logger); err != nil {
 		level.Error(logger).Log("err", err)
 		os.Exit(1)
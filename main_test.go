@@ -0,0 +1,114 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// cacheFixture stores the fixture for the device in the cache, as if it was
+// polled twice
+func cacheFixture(t testing.TB, filename string, device Device) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json := parseJSON(strings.ReplaceAll(string(data), "/dev/sdb", device.Name), jsonSourceFake)
+	labels := newSMARTDevice(json)
+	jsonCache.Store(device, JSONCache{JSON: json, Previous: json, LastCollect: time.Now(), Labels: &labels})
+	t.Cleanup(func() { jsonCache.Delete(device) })
+}
+
+// newCollectorRegistry registers the collector of the devices on a pedantic
+// registry. A paused collector serves the cached data only.
+func newCollectorRegistry(t testing.TB, devices []Device, paused bool) *prometheus.Registry {
+	groups, err := parseCollectGroups(strings.Join(metricGroups, ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collectGroups = groups
+	pollingPaused.Store(paused)
+	t.Cleanup(func() { pollingPaused.Store(false) })
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(&SMARTctlManagerCollector{Devices: devices, logger: log.NewNopLogger()})
+	return reg
+}
+
+// gatherFamily returns the metric family the registry gathers by name
+func gatherFamily(t testing.TB, reg prometheus.Gatherer, name string) *dto.MetricFamily {
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return family
+		}
+	}
+	return nil
+}
+
+// BenchmarkGather measures a scrape of many devices served from the cache
+func BenchmarkGather(b *testing.B) {
+	var devices []Device
+	for n := 0; n < 500; n++ {
+		device := Device{Name: fmt.Sprintf("/dev/sd%d", n), Info_Name: fmt.Sprintf("sd%d", n), Type: "sat"}
+		cacheFixture(b, "testdata/WDC_WD20EFRX-68EUZN0_17.json", device)
+		devices = append(devices, device)
+	}
+	reg := newCollectorRegistry(b, devices, true)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := reg.Gather(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCollectorDescribesPolledMetrics(t *testing.T) {
+	device := Device{Name: "/dev/sdb", Info_Name: "sdb", Type: "sat"}
+	// Registered before the first poll, the raw value deltas only appear
+	// with the second
+	reg := newCollectorRegistry(t, []Device{device}, true)
+	gatherFamily(t, reg, "smartctl_device_attribute_raw_value_delta")
+	cacheFixture(t, "testdata/WDC_WD20EFRX-68EUZN0_17.json", device)
+	if gatherFamily(t, reg, "smartctl_device_attribute_raw_value_delta") == nil {
+		t.Error("raw value deltas not gathered")
+	}
+}
+
+func TestCollectorPollRetries(t *testing.T) {
+	device := Device{Name: "/dev/sdy", Info_Name: "sdy", Type: "sat"}
+	syncDeviceState([]Device{device})
+	defer syncDeviceState(nil)
+	reg := newCollectorRegistry(t, []Device{device}, true)
+	if gatherFamily(t, reg, "smartctl_device_poll_retries_total") == nil {
+		t.Error("poll retries not gathered before the first retry")
+	}
+	countersOf(device).pollRetries.Add(1)
+	if gatherFamily(t, reg, "smartctl_device_poll_retries_total") == nil {
+		t.Error("poll retries not gathered after a retry")
+	}
+}
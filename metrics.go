@@ -34,6 +34,7 @@ var (
 		"Device info",
 		[]string{
 			"device",
+			"alias",
 			"interface",
 			"protocol",
 			"model_family",
@@ -52,17 +53,174 @@ var (
 		},
 		nil,
 	)
+	metricDeviceDataRestored = prometheus.NewDesc(
+		"smartctl_device_data_restored",
+		"Whether the device data was restored from the cache snapshot and not polled since the exporter started",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceDataAge = prometheus.NewDesc(
+		"smartctl_device_data_age_seconds",
+		"Time since the device data was collected by smartctl",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceClockSkew = prometheus.NewDesc(
+		"smartctl_device_clock_skew_seconds",
+		"Difference between the exporter clock and the local time reported by smartctl when the device data was stored",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceJSONBytes = prometheus.NewDesc(
+		"smartctl_device_json_bytes",
+		"Size of the cached smartctl json of the device",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDevicePath = prometheus.NewDesc(
+		"smartctl_device_path_info",
+		"Device path of devices identified by WWN or serial number",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"path",
+		},
+		nil,
+	)
+	metricDeviceNumber = prometheus.NewDesc(
+		"smartctl_device_number_info",
+		"Kernel major and minor number of the device node, to join node_exporter's node_disk_info on",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"dev_major",
+			"dev_minor",
+		},
+		nil,
+	)
+	metricATAVersion = prometheus.NewDesc(
+		"smartctl_ata_version_info",
+		"ATA and SATA versions supported by the device",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"ata_version",
+			"sata_version",
+		},
+		nil,
+	)
+	metricDeviceWriteCacheEnabled = prometheus.NewDesc(
+		"smartctl_device_write_cache_enabled",
+		"Whether the device write cache is enabled",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceReadLookaheadEnabled = prometheus.NewDesc(
+		"smartctl_device_read_lookahead_enabled",
+		"Whether the device read look-ahead is enabled",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceAPMLevel = prometheus.NewDesc(
+		"smartctl_device_apm_level",
+		"ATA Advanced Power Management level, from 1 for maximum power saving to 254 for maximum performance",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceAAMLevel = prometheus.NewDesc(
+		"smartctl_device_aam_level",
+		"ATA Automatic Acoustic Management level, from 128 for quietest to 254 for fastest",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
 	metricDeviceCount = prometheus.NewDesc(
 		"smartctl_devices",
 		"Number of devices configured or dynamically discovered",
 		[]string{},
 		nil,
 	)
+	metricDevicesFailing = prometheus.NewDesc(
+		"smartctl_devices_failing",
+		"Number of devices failing the SMART status check",
+		[]string{},
+		nil,
+	)
+	metricDevicesWarning = prometheus.NewDesc(
+		"smartctl_devices_warning",
+		"Number of devices passing the SMART status check with warnings, e.g. error log entries or attributes below threshold in the past",
+		[]string{},
+		nil,
+	)
+	metricPollingPaused = prometheus.NewDesc(
+		"smartctl_polling_paused",
+		"Whether polling devices is paused and cached data is served",
+		[]string{},
+		nil,
+	)
+	metricEffectivePollInterval = prometheus.NewDesc(
+		"smartctl_effective_poll_interval_seconds",
+		"Effective interval between smartctl polls of a device",
+		[]string{},
+		nil,
+	)
+	metricSmartctlResolveDuration = prometheus.NewDesc(
+		"smartctl_binary_resolve_seconds",
+		"Time looking up the smartctl binary, or the command wrapper, took at startup",
+		[]string{},
+		nil,
+	)
+	metricDevicePollInterval = prometheus.NewDesc(
+		"smartctl_device_poll_interval_seconds",
+		"Interval between smartctl polls applied to the device, after adaptive scaling and the minimum interval of rotational devices",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
 	metricDeviceCapacityBlocks = prometheus.NewDesc(
 		"smartctl_device_capacity_blocks",
 		"Device capacity in blocks",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -71,6 +229,8 @@ var (
 		"Device capacity in bytes",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -79,6 +239,8 @@ var (
 		"NVMe device total capacity bytes",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -87,24 +249,142 @@ var (
 		"Device block size",
 		[]string{
 			"device",
+			"alias",
+			"type",
 			"blocks_type",
 		},
 		nil,
 	)
+	metricDeviceSectorEmulation = prometheus.NewDesc(
+		"smartctl_device_sector_emulation",
+		"Sector layout of the device derived from its logical and physical block sizes, one of 512n, 512e or 4kn",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"mode",
+		},
+		nil,
+	)
+	metricDeviceTrimSupported = prometheus.NewDesc(
+		"smartctl_device_trim_supported",
+		"Whether the device supports TRIM or UNMAP",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceZoned = prometheus.NewDesc(
+		"smartctl_device_zoned",
+		"Zone model of SMR devices, one of host_managed, host_aware or drive_managed",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"zone_model",
+		},
+		nil,
+	)
 	metricDeviceInterfaceSpeed = prometheus.NewDesc(
 		"smartctl_device_interface_speed",
 		"Device interface speed, bits per second",
 		[]string{
 			"device",
+			"alias",
+			"type",
 			"speed_type",
 		},
 		nil,
 	)
+	metricSASPhyInvalidDword = prometheus.NewDesc(
+		"smartctl_sas_phy_invalid_dword_total",
+		"Number of invalid dwords received by the SAS phy outside of phy reset sequences",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"port",
+			"phy",
+		},
+		nil,
+	)
+	metricSASPhyRunningDisparityErrors = prometheus.NewDesc(
+		"smartctl_sas_phy_running_disparity_errors_total",
+		"Number of running disparity errors of dwords received by the SAS phy outside of phy reset sequences",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"port",
+			"phy",
+		},
+		nil,
+	)
+	metricSASPhyLossOfDwordSync = prometheus.NewDesc(
+		"smartctl_sas_phy_loss_of_dword_sync_total",
+		"Number of times the SAS phy lost dword synchronization and restarted the link reset sequence",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"port",
+			"phy",
+		},
+		nil,
+	)
+	metricSASPhyResetProblems = prometheus.NewDesc(
+		"smartctl_sas_phy_reset_problems_total",
+		"Number of SAS phy reset sequences that failed",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"port",
+			"phy",
+		},
+		nil,
+	)
+	metricDeviceBehindController = prometheus.NewDesc(
+		"smartctl_device_behind_controller",
+		"Whether the device is addressed through a RAID controller, labeled by the controller device and type",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"controller",
+			"controller_type",
+		},
+		nil,
+	)
+	metricDeviceQueueDepth = prometheus.NewDesc(
+		"smartctl_device_queue_depth",
+		"Command queue depth the kernel uses for the device",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceNCQEnabled = prometheus.NewDesc(
+		"smartctl_device_ncq_enabled",
+		"Whether native command queuing is in use for the ATA device, i.e. its queue depth is above 1",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
 	metricDeviceAttribute = prometheus.NewDesc(
 		"smartctl_device_attribute",
 		"Device attributes",
 		[]string{
 			"device",
+			"alias",
+			"type",
 			"attribute_name",
 			"attribute_flags_short",
 			"attribute_flags_long",
@@ -113,11 +393,93 @@ var (
 		},
 		nil,
 	)
+	metricDeviceAttributeFlags = prometheus.NewDesc(
+		"smartctl_device_attribute_flags",
+		"Device attribute flags (1 if the flag is set)",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"attribute_name",
+			"attribute_id",
+			"flag",
+		},
+		nil,
+	)
+	metricDeviceAttributesFailingNow = prometheus.NewDesc(
+		"smartctl_device_attributes_failing_now",
+		"Number of SMART attributes whose normalized value is at or below the threshold",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceAttributeStale = prometheus.NewDesc(
+		"smartctl_device_attribute_stale",
+		"Whether the device attribute is only updated by offline data collection, which never ran or does not run automatically, so its value may be frozen",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"attribute_name",
+			"attribute_id",
+		},
+		nil,
+	)
+	metricDeviceAttributeRawString = prometheus.NewDesc(
+		"smartctl_device_attribute_raw_string",
+		"Raw value of the device attribute as formatted by smartctl, for the attributes given by smartctl.attribute-raw-string",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"attribute_name",
+			"attribute_id",
+			"raw_string",
+		},
+		nil,
+	)
+	metricDeviceAttributeRawDelta = prometheus.NewDesc(
+		"smartctl_device_attribute_raw_value_delta",
+		"Change of the device attribute raw value between the last two polls",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"attribute_name",
+			"attribute_id",
+		},
+		nil,
+	)
+	metricDeviceUDMACRCErrors = prometheus.NewDesc(
+		"smartctl_device_udma_crc_errors_total",
+		"Device UDMA CRC error count (ATA attribute 199), usually caused by cabling or connection problems rather than media failure",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceReallocationEvents = prometheus.NewDesc(
+		"smartctl_device_reallocation_events_total",
+		"Device reallocation event count (ATA attribute 196), the number of remap operations as opposed to the reallocated sectors of attribute 5",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
 	metricDevicePowerOnSeconds = prometheus.NewDesc(
 		"smartctl_device_power_on_seconds",
-		"Device power on seconds",
+		"Device power on seconds, converted from the vendor specific units of drives smartctl does not know",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -126,6 +488,8 @@ var (
 		"Device rotation rate",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -134,15 +498,30 @@ var (
 		"Device temperature celsius",
 		[]string{
 			"device",
+			"alias",
+			"type",
 			"temperature_type",
 		},
 		nil,
 	)
+	metricDeviceTemperatureLimit = prometheus.NewDesc(
+		"smartctl_device_temperature_limit_celsius",
+		"Temperature limits reported by the device",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"kind",
+		},
+		nil,
+	)
 	metricDevicePowerCycleCount = prometheus.NewDesc(
 		"smartctl_device_power_cycle_count",
 		"Device power cycle count",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -151,6 +530,18 @@ var (
 		"Device write percentage used",
 		[]string{
 			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDevicePercentageUsedRatio = prometheus.NewDesc(
+		"smartctl_device_percentage_used_ratio",
+		"Ratio of the rated endurance used by the device, exceeds 1 past the rated endurance",
+		[]string{
+			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -159,6 +550,8 @@ var (
 		"Normalized percentage (0 to 100%) of the remaining spare capacity available",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -167,6 +560,8 @@ var (
 		"When the Available Spare falls below the threshold indicated in this field, an asynchronous event completion may occur. The value is indicated as a normalized percentage (0 to 100%)",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -175,6 +570,8 @@ var (
 		"This field indicates critical warnings for the state of the controller",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -183,6 +580,8 @@ var (
 		"Contains the number of occurrences where the controller detected an unrecovered data integrity error. Errors such as uncorrectable ECC, CRC checksum failure, or LBA tag mismatch are included in this field",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -191,6 +590,136 @@ var (
 		"Contains the number of Error Information log entries over the life of the controller",
 		[]string{
 			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricNvmeErrorLogEntries = prometheus.NewDesc(
+		"smartctl_nvme_error_log_entries_total",
+		"Number of Error Information log entries over the life of the controller, kept monotonic across resets of the count",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricNvmeUnsafeShutdowns = prometheus.NewDesc(
+		"smartctl_nvme_unsafe_shutdowns_total",
+		"Contains the number of unsafe shutdowns, i.e. power losses without a shutdown notification being received by the controller",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricNvmeNamespaceUtilizationBytes = prometheus.NewDesc(
+		"smartctl_nvme_namespace_utilization_bytes",
+		"Number of bytes allocated in the NVMe namespace",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"namespace",
+		},
+		nil,
+	)
+	metricNvmePCIeLinkSpeed = prometheus.NewDesc(
+		"smartctl_nvme_pcie_link_speed_gts",
+		"PCIe link speed of the NVMe controller in GT/s, as negotiated (current) and supported (max)",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"link",
+		},
+		nil,
+	)
+	metricNvmePCIeLinkWidth = prometheus.NewDesc(
+		"smartctl_nvme_pcie_link_width",
+		"PCIe link width of the NVMe controller in lanes, as negotiated (current) and supported (max)",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"link",
+		},
+		nil,
+	)
+	metricNvmeBadNANDBlocks = prometheus.NewDesc(
+		"smartctl_nvme_bad_nand_blocks",
+		"Number of bad user or system NAND blocks, as raw count and normalized value",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"kind",
+			"value_type",
+		},
+		nil,
+	)
+	metricNvmeOCPPhysicalMediaUnitsWritten = prometheus.NewDesc(
+		"smartctl_nvme_ocp_physical_media_units_written_bytes",
+		"Number of bytes written to the NAND media, from the OCP SMART extended log",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricNvmeOCPPhysicalMediaUnitsRead = prometheus.NewDesc(
+		"smartctl_nvme_ocp_physical_media_units_read_bytes",
+		"Number of bytes read from the NAND media, from the OCP SMART extended log",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricNvmeEnduranceGroupPercentageUsed = prometheus.NewDesc(
+		"smartctl_nvme_endurance_group_percentage_used_ratio",
+		"Ratio of the rated endurance used by the NVMe endurance group, may exceed 1",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"endurance_group",
+		},
+		nil,
+	)
+	metricNvmeEnduranceGroupAvailableSpare = prometheus.NewDesc(
+		"smartctl_nvme_endurance_group_available_spare_ratio",
+		"Ratio of the spare capacity remaining in the NVMe endurance group",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"endurance_group",
+		},
+		nil,
+	)
+	metricNvmeEnduranceGroupAvailableSpareThreshold = prometheus.NewDesc(
+		"smartctl_nvme_endurance_group_available_spare_threshold_ratio",
+		"Ratio of spare capacity of the NVMe endurance group below which it is considered critical",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"endurance_group",
+		},
+		nil,
+	)
+	metricNvmeOCPXORRecoveries = prometheus.NewDesc(
+		"smartctl_nvme_ocp_xor_recoveries_total",
+		"Number of times XOR was used to recover data, from the OCP SMART extended log",
+		[]string{
+			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -199,6 +728,8 @@ var (
 		"",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -207,6 +738,18 @@ var (
 		"",
 		[]string{
 			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceFirmwareUpdateRecommended = prometheus.NewDesc(
+		"smartctl_device_firmware_update_recommended",
+		"Whether the device runs firmware with known issues or smartctl recommends a firmware update",
+		[]string{
+			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -215,6 +758,38 @@ var (
 		"General smart status",
 		[]string{
 			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceHealthScore = prometheus.NewDesc(
+		"smartctl_device_health_score",
+		"Health score from 0 to 100 of the device, given by the critical attribute closest to its threshold",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDevicePrefail = prometheus.NewDesc(
+		"smartctl_device_prefail",
+		"Whether prefail attributes are currently at or below their threshold (exit status bit 4)",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDevicePastPrefail = prometheus.NewDesc(
+		"smartctl_device_past_prefail",
+		"Whether usage or prefail attributes have been at or below their threshold in the past (exit status bit 5)",
+		[]string{
+			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -223,6 +798,88 @@ var (
 		"Exit status of smartctl on device",
 		[]string{
 			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceNewlyDiscovered = prometheus.NewDesc(
+		"smartctl_device_newly_discovered",
+		"Whether the device was discovered by a rescan and is warming up or within its first interval",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceAccessible = prometheus.NewDesc(
+		"smartctl_device_accessible",
+		"Whether smartctl could open the device when it was probed at startup",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceArgParseError = prometheus.NewDesc(
+		"smartctl_device_arg_parse_error",
+		"Whether the smartctl command line for the device did not parse (exit status bit 0), usually an invalid device type",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDevicePollRetries = prometheus.NewDesc(
+		"smartctl_device_poll_retries_total",
+		"Number of times polling the device with smartctl was retried after a failure",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceEmptyOutput = prometheus.NewDesc(
+		"smartctl_device_empty_output_total",
+		"Number of times smartctl succeeded for the device without writing any output",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceSMARTAvailable = prometheus.NewDesc(
+		"smartctl_device_smart_available",
+		"Whether the device supports SMART",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceSMARTEnabled = prometheus.NewDesc(
+		"smartctl_device_smart_enabled",
+		"Whether SMART is enabled on the device, it can be enabled with smartctl -s on",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceNoSMARTSupport = prometheus.NewDesc(
+		"smartctl_device_no_smart_support",
+		"Whether the device provides no SMART data, e.g. loop and virtual devices",
+		[]string{
+			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -231,6 +888,69 @@ var (
 		"Device state (0=active, 1=standby, 2=sleep, 3=dst, 4=offline, 5=sct)",
 		[]string{
 			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricATAOfflineDataCollectionStatus = prometheus.NewDesc(
+		"smartctl_ata_offline_data_collection_status",
+		"ATA offline data collection status value, lower 7 bits (0=never started, 2=completed without error, 3=in progress, 4=suspended, 5=aborted by host, 6=aborted by device with fatal error)",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricATAOfflineDataCollectionAutoEnabled = prometheus.NewDesc(
+		"smartctl_ata_offline_data_collection_auto_enabled",
+		"Whether ATA automatic offline data collection is enabled",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceUncorrectedErrors = prometheus.NewDesc(
+		"smartctl_device_uncorrected_errors_total",
+		"Errors the device could not correct, across protocols: Reported_Uncorrect (ATA), media and data integrity errors (NVMe), uncorrected read, write and verify errors (SCSI)",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceLifeRemaining = prometheus.NewDesc(
+		"smartctl_device_estimated_life_remaining_ratio",
+		"Remaining rated endurance of the SSD, derived from the percentage used",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricDeviceEstimatedEndOfLife = prometheus.NewDesc(
+		"smartctl_device_estimated_end_of_life_timestamp_seconds",
+		"Time the SSD is projected to reach its rated endurance at the write rate observed since the exporter started",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricATASelfTestPollingMinutes = prometheus.NewDesc(
+		"smartctl_ata_self_test_polling_minutes",
+		"Recommended polling time of the ATA self-test in minutes, an estimate of its duration",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"test",
 		},
 		nil,
 	)
@@ -239,6 +959,8 @@ var (
 		"Device statistics",
 		[]string{
 			"device",
+			"alias",
+			"type",
 			"statistic_table",
 			"statistic_name",
 			"statistic_flags_short",
@@ -251,15 +973,41 @@ var (
 		"Device SMART error log count",
 		[]string{
 			"device",
+			"alias",
+			"type",
 			"error_log_type",
 		},
 		nil,
 	)
+	metricATALastErrorLifetimeHours = prometheus.NewDesc(
+		"smartctl_ata_last_error_lifetime_hours",
+		"Power on hours of the device at the most recent ATA error log entry, labeled by the error type",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"error_type",
+		},
+		nil,
+	)
 	metricDeviceSelfTestLogCount = prometheus.NewDesc(
 		"smartctl_device_self_test_log_count",
 		"Device SMART self test log count",
 		[]string{
 			"device",
+			"alias",
+			"type",
+			"self_test_log_type",
+		},
+		nil,
+	)
+	metricDeviceSelfTestLogWrapped = prometheus.NewDesc(
+		"smartctl_device_self_test_log_wrapped",
+		"Whether the self test log is full and its oldest entries are overwritten",
+		[]string{
+			"device",
+			"alias",
+			"type",
 			"self_test_log_type",
 		},
 		nil,
@@ -269,24 +1017,100 @@ var (
 		"Device SMART self test log error count",
 		[]string{
 			"device",
+			"alias",
+			"type",
 			"self_test_log_type",
 		},
 		nil,
 	)
+	metricATALogDirectorySectors = prometheus.NewDesc(
+		"smartctl_ata_log_directory_sectors",
+		"Number of sectors of the selected ATA logs according to the log directory",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"log_address",
+			"log_name",
+			"log_type",
+		},
+		nil,
+	)
 	metricDeviceERCSeconds = prometheus.NewDesc(
 		"smartctl_device_erc_seconds",
 		"Device SMART Error Recovery Control Seconds",
 		[]string{
 			"device",
+			"alias",
+			"type",
 			"op_type",
 		},
 		nil,
 	)
+	metricSCSIDeviceInfo = prometheus.NewDesc(
+		"smartctl_scsi_device_info",
+		"SCSI device info",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"vendor",
+			"product",
+			"revision",
+			"version",
+			"logical_unit_id",
+			"transport_protocol",
+		},
+		nil,
+	)
 	metricSCSIGrownDefectList = prometheus.NewDesc(
 		"smartctl_scsi_grown_defect_list",
 		"Device SCSI grown defect list counter",
 		[]string{
 			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricSCSIDefectListCount = prometheus.NewDesc(
+		"smartctl_scsi_defect_list_count",
+		"Number of entries in the SCSI defect lists, primary for the factory defects and grown for those found in use",
+		[]string{
+			"device",
+			"alias",
+			"type",
+			"source",
+		},
+		nil,
+	)
+	metricSCSIBackgroundScanStatus = prometheus.NewDesc(
+		"smartctl_scsi_background_scan_status",
+		"Status of the SCSI background medium scan as reported by the background scan results log page",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricSCSIBackgroundScanProgress = prometheus.NewDesc(
+		"smartctl_scsi_background_scan_progress_percent",
+		"Progress of the running SCSI background medium scan",
+		[]string{
+			"device",
+			"alias",
+			"type",
+		},
+		nil,
+	)
+	metricSCSIBackgroundScans = prometheus.NewDesc(
+		"smartctl_scsi_background_scans_total",
+		"Number of SCSI background medium scans performed",
+		[]string{
+			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -295,6 +1119,8 @@ var (
 		"Read Errors Corrected by ReReads/ReWrites",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -303,6 +1129,8 @@ var (
 		"Read Errors Corrected by ECC Fast",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -311,6 +1139,8 @@ var (
 		"Read Errors Corrected by ECC Delayed",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -319,6 +1149,8 @@ var (
 		"Read Total Uncorrected Errors",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -327,6 +1159,8 @@ var (
 		"Write Errors Corrected by ReReads/ReWrites",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -335,6 +1169,8 @@ var (
 		"Write Errors Corrected by ECC Fast",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -343,6 +1179,8 @@ var (
 		"Write Errors Corrected by ECC Delayed",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
@@ -351,7 +1189,126 @@ var (
 		"Write Total Uncorrected Errors",
 		[]string{
 			"device",
+			"alias",
+			"type",
 		},
 		nil,
 	)
 )
+
+// metricDescs lists every descriptor above. The collector describes them up
+// front, as most only appear once the data they are derived from was polled.
+var metricDescs = []*prometheus.Desc{
+	metricSmartctlVersion,
+	metricDeviceModel,
+	metricDeviceDataRestored,
+	metricDeviceDataAge,
+	metricDeviceClockSkew,
+	metricDeviceJSONBytes,
+	metricDevicePath,
+	metricDeviceNumber,
+	metricATAVersion,
+	metricDeviceWriteCacheEnabled,
+	metricDeviceReadLookaheadEnabled,
+	metricDeviceAPMLevel,
+	metricDeviceAAMLevel,
+	metricDeviceCount,
+	metricDevicesFailing,
+	metricDevicesWarning,
+	metricPollingPaused,
+	metricEffectivePollInterval,
+	metricSmartctlResolveDuration,
+	metricDevicePollInterval,
+	metricDeviceCapacityBlocks,
+	metricDeviceCapacityBytes,
+	metricDeviceTotalCapacityBytes,
+	metricDeviceBlockSize,
+	metricDeviceSectorEmulation,
+	metricDeviceTrimSupported,
+	metricDeviceZoned,
+	metricDeviceInterfaceSpeed,
+	metricSASPhyInvalidDword,
+	metricSASPhyRunningDisparityErrors,
+	metricSASPhyLossOfDwordSync,
+	metricSASPhyResetProblems,
+	metricDeviceBehindController,
+	metricDeviceQueueDepth,
+	metricDeviceNCQEnabled,
+	metricDeviceAttribute,
+	metricDeviceAttributeFlags,
+	metricDeviceAttributesFailingNow,
+	metricDeviceAttributeStale,
+	metricDeviceAttributeRawString,
+	metricDeviceAttributeRawDelta,
+	metricDeviceUDMACRCErrors,
+	metricDeviceReallocationEvents,
+	metricDevicePowerOnSeconds,
+	metricDeviceRotationRate,
+	metricDeviceTemperature,
+	metricDeviceTemperatureLimit,
+	metricDevicePowerCycleCount,
+	metricDevicePercentageUsed,
+	metricDevicePercentageUsedRatio,
+	metricDeviceAvailableSpare,
+	metricDeviceAvailableSpareThreshold,
+	metricDeviceCriticalWarning,
+	metricDeviceMediaErrors,
+	metricDeviceNumErrLogEntries,
+	metricNvmeErrorLogEntries,
+	metricNvmeUnsafeShutdowns,
+	metricNvmeNamespaceUtilizationBytes,
+	metricNvmePCIeLinkSpeed,
+	metricNvmePCIeLinkWidth,
+	metricNvmeBadNANDBlocks,
+	metricNvmeOCPPhysicalMediaUnitsWritten,
+	metricNvmeOCPPhysicalMediaUnitsRead,
+	metricNvmeEnduranceGroupPercentageUsed,
+	metricNvmeEnduranceGroupAvailableSpare,
+	metricNvmeEnduranceGroupAvailableSpareThreshold,
+	metricNvmeOCPXORRecoveries,
+	metricDeviceBytesRead,
+	metricDeviceBytesWritten,
+	metricDeviceFirmwareUpdateRecommended,
+	metricDeviceSmartStatus,
+	metricDeviceHealthScore,
+	metricDevicePrefail,
+	metricDevicePastPrefail,
+	metricDeviceExitStatus,
+	metricDeviceNewlyDiscovered,
+	metricDeviceAccessible,
+	metricDeviceArgParseError,
+	metricDevicePollRetries,
+	metricDeviceEmptyOutput,
+	metricDeviceSMARTAvailable,
+	metricDeviceSMARTEnabled,
+	metricDeviceNoSMARTSupport,
+	metricDeviceState,
+	metricATAOfflineDataCollectionStatus,
+	metricATAOfflineDataCollectionAutoEnabled,
+	metricDeviceUncorrectedErrors,
+	metricDeviceLifeRemaining,
+	metricDeviceEstimatedEndOfLife,
+	metricATASelfTestPollingMinutes,
+	metricDeviceStatistics,
+	metricDeviceErrorLogCount,
+	metricATALastErrorLifetimeHours,
+	metricDeviceSelfTestLogCount,
+	metricDeviceSelfTestLogWrapped,
+	metricDeviceSelfTestLogErrorCount,
+	metricATALogDirectorySectors,
+	metricDeviceERCSeconds,
+	metricSCSIDeviceInfo,
+	metricSCSIGrownDefectList,
+	metricSCSIDefectListCount,
+	metricSCSIBackgroundScanStatus,
+	metricSCSIBackgroundScanProgress,
+	metricSCSIBackgroundScans,
+	metricReadErrorsCorrectedByRereadsRewrites,
+	metricReadErrorsCorrectedByEccFast,
+	metricReadErrorsCorrectedByEccDelayed,
+	metricReadTotalUncorrectedErrors,
+	metricWriteErrorsCorrectedByRereadsRewrites,
+	metricWriteErrorsCorrectedByEccFast,
+	metricWriteErrorsCorrectedByEccDelayed,
+	metricWriteTotalUncorrectedErrors,
+}
@@ -0,0 +1,280 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/version"
+)
+
+// otlpPushTimeout bounds a single push to the OTLP endpoint
+const otlpPushTimeout = 30 * time.Second
+
+// The OTLP/HTTP JSON encoding of the metrics, limited to the fields the
+// exporter fills. 64 bit integers are encoded as strings, as required by the
+// protobuf JSON mapping.
+type (
+	otlpMetricsRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	otlpAttribute struct {
+		Key   string             `json:"key"`
+		Value otlpAttributeValue `json:"value"`
+	}
+	otlpAttributeValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpMetric struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Gauge       *otlpGauge     `json:"gauge,omitempty"`
+		Sum         *otlpSum       `json:"sum,omitempty"`
+		Histogram   *otlpHistogram `json:"histogram,omitempty"`
+		Summary     *otlpSummary   `json:"summary,omitempty"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+	}
+	otlpSum struct {
+		DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+		AggregationTemporality int                   `json:"aggregationTemporality"`
+		IsMonotonic            bool                  `json:"isMonotonic"`
+	}
+	otlpNumberDataPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		AsDouble          float64         `json:"asDouble"`
+	}
+	otlpHistogram struct {
+		DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+		AggregationTemporality int                      `json:"aggregationTemporality"`
+	}
+	otlpHistogramDataPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		Count             string          `json:"count"`
+		Sum               float64         `json:"sum"`
+		BucketCounts      []string        `json:"bucketCounts"`
+		ExplicitBounds    []float64       `json:"explicitBounds"`
+	}
+	otlpSummary struct {
+		DataPoints []otlpSummaryDataPoint `json:"dataPoints"`
+	}
+	otlpSummaryDataPoint struct {
+		Attributes        []otlpAttribute     `json:"attributes"`
+		StartTimeUnixNano string              `json:"startTimeUnixNano"`
+		TimeUnixNano      string              `json:"timeUnixNano"`
+		Count             string              `json:"count"`
+		Sum               float64             `json:"sum"`
+		QuantileValues    []otlpQuantileValue `json:"quantileValues"`
+	}
+	otlpQuantileValue struct {
+		Quantile float64 `json:"quantile"`
+		Value    float64 `json:"value"`
+	}
+)
+
+// otlpCumulative is the cumulative aggregation temporality, as counters and
+// histograms only ever grow while the exporter runs
+const otlpCumulative = 2
+
+// PushOTLP periodically pushes the collected metrics to an OTLP/HTTP
+// endpoint, e.g. http://localhost:4318/v1/metrics
+func PushOTLP(logger log.Logger, gatherer prometheus.Gatherer, endpoint string, interval time.Duration) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		level.Warn(logger).Log("msg", "Unable to determine hostname for the host.name attribute", "err", err)
+	}
+	start := time.Now()
+	for {
+		if err := pushOTLPOnce(gatherer, endpoint, hostname, start); err != nil {
+			level.Error(logger).Log("msg", "Pushing metrics to the OTLP endpoint failed", "endpoint", endpoint, "err", err)
+		} else {
+			level.Debug(logger).Log("msg", "Pushed metrics to the OTLP endpoint", "endpoint", endpoint)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func pushOTLPOnce(gatherer prometheus.Gatherer, endpoint string, hostname string, start time.Time) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(otlpRequest(families, hostname, start, time.Now()))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), otlpPushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// otlpRequest converts the gathered metric families to an OTLP export
+// request. Counters become monotonic sums and untyped metrics gauges.
+func otlpRequest(families []*dto.MetricFamily, hostname string, start time.Time, now time.Time) otlpMetricsRequest {
+	startNano := strconv.FormatInt(start.UnixNano(), 10)
+	var metrics []otlpMetric
+	for _, family := range families {
+		metric := otlpMetric{Name: family.GetName(), Description: family.GetHelp()}
+		var numbers []otlpNumberDataPoint
+		for _, m := range family.GetMetric() {
+			attributes := otlpLabels(m.GetLabel())
+			timestamp := now
+			if m.TimestampMs != nil {
+				timestamp = time.UnixMilli(m.GetTimestampMs())
+			}
+			timeNano := strconv.FormatInt(timestamp.UnixNano(), 10)
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				numbers = append(numbers, otlpNumberDataPoint{attributes, startNano, timeNano, m.GetCounter().GetValue()})
+			case dto.MetricType_GAUGE:
+				numbers = append(numbers, otlpNumberDataPoint{attributes, "", timeNano, m.GetGauge().GetValue()})
+			case dto.MetricType_UNTYPED:
+				numbers = append(numbers, otlpNumberDataPoint{attributes, "", timeNano, m.GetUntyped().GetValue()})
+			case dto.MetricType_HISTOGRAM:
+				if metric.Histogram == nil {
+					metric.Histogram = &otlpHistogram{AggregationTemporality: otlpCumulative}
+				}
+				metric.Histogram.DataPoints = append(metric.Histogram.DataPoints, otlpHistogramPoint(m.GetHistogram(), attributes, startNano, timeNano))
+			case dto.MetricType_SUMMARY:
+				if metric.Summary == nil {
+					metric.Summary = &otlpSummary{}
+				}
+				point := otlpSummaryDataPoint{
+					Attributes:        attributes,
+					StartTimeUnixNano: startNano,
+					TimeUnixNano:      timeNano,
+					Count:             strconv.FormatUint(m.GetSummary().GetSampleCount(), 10),
+					Sum:               m.GetSummary().GetSampleSum(),
+				}
+				for _, quantile := range m.GetSummary().GetQuantile() {
+					if math.IsNaN(quantile.GetValue()) {
+						continue
+					}
+					point.QuantileValues = append(point.QuantileValues, otlpQuantileValue{quantile.GetQuantile(), quantile.GetValue()})
+				}
+				metric.Summary.DataPoints = append(metric.Summary.DataPoints, point)
+			}
+		}
+		// JSON has no encoding for NaN and infinities, drop such samples
+		numbers = slices.DeleteFunc(numbers, func(point otlpNumberDataPoint) bool {
+			return math.IsNaN(point.AsDouble) || math.IsInf(point.AsDouble, 0)
+		})
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			metric.Sum = &otlpSum{DataPoints: numbers, AggregationTemporality: otlpCumulative, IsMonotonic: true}
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			metric.Gauge = &otlpGauge{DataPoints: numbers}
+		}
+		if len(numbers) == 0 && metric.Histogram == nil && metric.Summary == nil {
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	resource := otlpResource{Attributes: []otlpAttribute{
+		{Key: "service.name", Value: otlpAttributeValue{"smartctl_exporter"}},
+		{Key: "service.version", Value: otlpAttributeValue{version.Version}},
+	}}
+	if hostname != "" {
+		resource.Attributes = append(resource.Attributes, otlpAttribute{Key: "host.name", Value: otlpAttributeValue{hostname}})
+	}
+	return otlpMetricsRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: resource,
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: "smartctl_exporter", Version: version.Version},
+			Metrics: metrics,
+		}},
+	}}}
+}
+
+// otlpHistogramPoint converts the cumulative Prometheus buckets to the
+// per-bucket counts of OTLP, the last one counting the samples above all
+// bounds
+func otlpHistogramPoint(histogram *dto.Histogram, attributes []otlpAttribute, startNano string, timeNano string) otlpHistogramDataPoint {
+	point := otlpHistogramDataPoint{
+		Attributes:        attributes,
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      timeNano,
+		Count:             strconv.FormatUint(histogram.GetSampleCount(), 10),
+		Sum:               histogram.GetSampleSum(),
+		BucketCounts:      []string{},
+		ExplicitBounds:    []float64{},
+	}
+	var previous uint64
+	for _, bucket := range histogram.GetBucket() {
+		if math.IsInf(bucket.GetUpperBound(), 1) {
+			continue
+		}
+		point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(bucket.GetCumulativeCount()-previous, 10))
+		previous = bucket.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(histogram.GetSampleCount()-previous, 10))
+	return point
+}
+
+func otlpLabels(labels []*dto.LabelPair) []otlpAttribute {
+	attributes := []otlpAttribute{}
+	for _, label := range labels {
+		attributes = append(attributes, otlpAttribute{Key: label.GetName(), Value: otlpAttributeValue{label.GetValue()}})
+	}
+	return attributes
+}
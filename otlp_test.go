@@ -0,0 +1,76 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPushOTLP(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_temperature", Help: "Temperature"}, []string{"device"})
+	gauge.WithLabelValues("sda").Set(42)
+	gauge.WithLabelValues("sdb").Set(math.NaN())
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_polls_total"})
+	counter.Add(3)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_duration_seconds", Buckets: []float64{1, 10}})
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+	histogram.Observe(50)
+	reg.MustRegister(gauge, counter, histogram)
+
+	var request otlpMetricsRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+	if err := pushOTLPOnce(reg, server.URL, "host", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := map[string]otlpMetric{}
+	for _, metric := range request.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		metrics[metric.Name] = metric
+	}
+	if temperature := metrics["test_temperature"].Gauge; temperature == nil || len(temperature.DataPoints) != 1 || temperature.DataPoints[0].AsDouble != 42 {
+		t.Errorf("test_temperature = %+v, want only the sda gauge", metrics["test_temperature"])
+	}
+	if polls := metrics["test_polls_total"].Sum; polls == nil || !polls.IsMonotonic || polls.DataPoints[0].AsDouble != 3 {
+		t.Errorf("test_polls_total = %+v, want a monotonic sum", metrics["test_polls_total"])
+	}
+	duration := metrics["test_duration_seconds"].Histogram
+	if duration == nil {
+		t.Fatal("test_duration_seconds is no histogram")
+	}
+	if counts := duration.DataPoints[0].BucketCounts; len(counts) != 3 || counts[0] != "1" || counts[1] != "1" || counts[2] != "1" {
+		t.Errorf("bucket counts = %v, want 1 per bucket", counts)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := pushOTLPOnce(reg, failing.URL, "host", time.Now()); err == nil {
+		t.Error("no error for a rejected push")
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/tidwall/gjson"
+)
+
+// plaintextMode is set at startup when smartctl lacks --json and the
+// plaintext fallback is enabled
+var plaintextMode bool
+
+var (
+	plaintextVersionRegexp   = regexp.MustCompile(`^smartctl (\d+)\.(\d+)`)
+	plaintextScanRegexp      = regexp.MustCompile(`^(\S+)\s+-d\s+(\S+)\s+#\s+([^,]+)`)
+	plaintextInfoRegexp      = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9 /.-]*?):\s+(.+)$`)
+	plaintextAttributeRegexp = regexp.MustCompile(`^\s*(\d+)\s+(\S+)\s+0x([0-9a-fA-F]+)\s+(\d+)\s+(\d+)\s+(\d+|---)\s+\S+\s+\S+\s+\S+\s+(\d+)(.*)$`)
+	plaintextSCSIHoursRegexp = regexp.MustCompile(`^Accumulated power on time, hours:minutes (\d+):(\d+)`)
+	plaintextNumberRegexp    = regexp.MustCompile(`^[\d,]+`)
+)
+
+// ATA attribute flag bits, as in ataAttributeFlags
+var plaintextAttributeFlags = []struct {
+	name   string
+	letter string
+}{
+	{"prefailure", "P"},
+	{"updated_online", "O"},
+	{"performance", "S"},
+	{"error_rate", "R"},
+	{"event_count", "C"},
+	{"auto_keep", "K"},
+}
+
+// smartctlSupportsJSON reports whether smartctl supports --json, which
+// smartmontools added in 7.0
+func smartctlSupportsJSON(logger log.Logger) bool {
+	out, _, err := runSMARTctl("--version")
+	if err != nil {
+		level.Warn(logger).Log("msg", "Unable to determine the smartctl version", "err", err)
+		return true
+	}
+	match := plaintextVersionRegexp.FindStringSubmatch(string(out))
+	if match == nil {
+		return true
+	}
+	major, _ := strconv.Atoi(match[1])
+	return major >= 7
+}
+
+// readSMARTctlPlaintextDevices scans for devices with the plaintext output,
+// returning them in the layout of the json scan
+func readSMARTctlPlaintextDevices(logger log.Logger) gjson.Result {
+	level.Debug(logger).Log("msg", "Scanning for devices with plaintext output")
+	out, stderr, err := runSMARTctl("--scan")
+	if err != nil {
+		level.Warn(logger).Log("msg", "S.M.A.R.T. output reading error", "err", err, "stderr", stderr)
+		return gjson.Result{}
+	}
+	var devices []map[string]string
+	for _, line := range strings.Split(string(out), "\n") {
+		if match := plaintextScanRegexp.FindStringSubmatch(line); match != nil {
+			devices = append(devices, map[string]string{
+				"name":      match[1],
+				"type":      match[2],
+				"info_name": strings.TrimSpace(match[3]),
+			})
+		}
+	}
+	data, _ := json.Marshal(map[string]interface{}{"devices": devices})
+	return gjson.ParseBytes(data)
+}
+
+// readSMARTctlPlaintext polls the device with the plaintext output
+func readSMARTctlPlaintext(logger log.Logger, device Device) (gjson.Result, bool) {
+	start := time.Now()
+	out, stderr, err := runSMARTctl("--info", "--health", "--attributes", "--tolerance=verypermissive", "--nocheck=standby", "--device="+device.Type, device.Name)
+	var exitStatus int64
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitStatus = int64(exitErr.ExitCode())
+	} else if err != nil {
+		level.Warn(logger).Log("msg", "S.M.A.R.T. output reading", "err", err, "stderr", stderr, "device", device.Info_Name)
+		return gjson.Result{}, false
+	}
+	json := parsePlaintext(device, string(out), exitStatus)
+	ok := resultCodeIsOk(logger, device, exitStatus&^ignoredExitBits)
+	recordPoll(device, start, exitStatus, ok)
+	level.Debug(logger).Log("msg", "Collected S.M.A.R.T. plaintext data", "device", device.Info_Name, "duration", time.Since(start))
+	return json, ok
+}
+
+// plaintextNumber parses the leading number of a value, e.g. "1,234 [1.2 TB]"
+func plaintextNumber(value string) (int64, bool) {
+	match := plaintextNumberRegexp.FindString(strings.TrimSpace(value))
+	if match == "" {
+		return 0, false
+	}
+	number, err := strconv.ParseInt(strings.ReplaceAll(match, ",", ""), 10, 64)
+	return number, err == nil
+}
+
+// parsePlaintext maps the core of the plaintext smartctl output, device
+// identity, health, attributes, temperature, power on hours and power
+// cycles, to the smartctl json layout
+func parsePlaintext(device Device, out string, exitStatus int64) gjson.Result {
+	protocol := "ATA"
+	switch device.Type {
+	case "nvme":
+		protocol = "NVMe"
+	case "scsi":
+		protocol = "SCSI"
+	}
+	data := map[string]interface{}{
+		"device": map[string]interface{}{
+			"name":      device.Name,
+			"info_name": device.Info_Name,
+			"type":      device.Type,
+			"protocol":  protocol,
+		},
+		"smartctl": map[string]interface{}{"exit_status": exitStatus},
+	}
+	if match := plaintextVersionRegexp.FindStringSubmatch(out); match != nil {
+		major, _ := strconv.Atoi(match[1])
+		minor, _ := strconv.Atoi(match[2])
+		data["smartctl"].(map[string]interface{})["version"] = []int{major, minor}
+	}
+
+	var table []interface{}
+	attributes := map[int64]int64{}
+	for _, line := range strings.Split(out, "\n") {
+		if match := plaintextAttributeRegexp.FindStringSubmatch(line); match != nil {
+			id, _ := strconv.ParseInt(match[1], 10, 64)
+			flags, _ := strconv.ParseInt(match[3], 16, 64)
+			raw, _ := strconv.ParseInt(match[7], 10, 64)
+			attributes[id] = raw
+			flagsJSON := map[string]interface{}{"value": flags}
+			var flagsString strings.Builder
+			for bit, flag := range plaintextAttributeFlags {
+				set := flags&(1<<bit) != 0
+				flagsJSON[flag.name] = set
+				if set {
+					flagsString.WriteString(flag.letter)
+				} else {
+					flagsString.WriteString("-")
+				}
+			}
+			flagsJSON["string"] = flagsString.String()
+			attribute := map[string]interface{}{
+				"id":    id,
+				"name":  match[2],
+				"flags": flagsJSON,
+				"raw":   map[string]interface{}{"value": raw, "string": strings.TrimSpace(match[7] + match[8])},
+			}
+			for key, index := range map[string]int{"value": 4, "worst": 5, "thresh": 6} {
+				if number, err := strconv.ParseInt(match[index], 10, 64); err == nil {
+					attribute[key] = number
+				}
+			}
+			table = append(table, attribute)
+			continue
+		}
+		if match := plaintextSCSIHoursRegexp.FindStringSubmatch(line); match != nil {
+			hours, _ := strconv.ParseInt(match[1], 10, 64)
+			minutes, _ := strconv.ParseInt(match[2], 10, 64)
+			data["power_on_time"] = map[string]interface{}{"hours": hours, "minutes": minutes}
+			continue
+		}
+		match := plaintextInfoRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		key, value := match[1], strings.TrimSpace(match[2])
+		switch key {
+		case "Device Model", "Model Number", "Product":
+			data["model_name"] = value
+		case "Model Family":
+			data["model_family"] = value
+		case "Serial Number", "Serial number":
+			data["serial_number"] = value
+		case "Firmware Version", "Revision":
+			data["firmware_version"] = value
+		case "SMART overall-health self-assessment test result":
+			data["smart_status"] = map[string]interface{}{"passed": value == "PASSED"}
+		case "SMART Health Status":
+			data["smart_status"] = map[string]interface{}{"passed": value == "OK"}
+		case "Temperature", "Current Drive Temperature":
+			if number, ok := plaintextNumber(value); ok {
+				data["temperature"] = map[string]interface{}{"current": number}
+			}
+		case "Power On Hours":
+			if number, ok := plaintextNumber(value); ok {
+				data["power_on_time"] = map[string]interface{}{"hours": number}
+			}
+		case "Power Cycles":
+			if number, ok := plaintextNumber(value); ok {
+				data["power_cycle_count"] = number
+			}
+		}
+	}
+
+	if table != nil {
+		data["ata_smart_attributes"] = map[string]interface{}{"table": table}
+		for _, id := range []int64{194, 190} {
+			if raw, ok := attributes[id]; ok {
+				// The low byte holds the current temperature
+				data["temperature"] = map[string]interface{}{"current": raw & 0xff}
+				break
+			}
+		}
+		if raw, ok := attributes[9]; ok {
+			data["power_on_time"] = map[string]interface{}{"hours": raw}
+		}
+		if raw, ok := attributes[12]; ok {
+			data["power_cycle_count"] = raw
+		}
+	}
+
+	encoded, _ := json.Marshal(data)
+	return gjson.ParseBytes(encoded)
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+const plaintextATAOutput = `smartctl 6.6 2016-05-31 r4324 [x86_64-linux-4.9.0-8-amd64] (local build)
+Copyright (C) 2002-16, Bruce Allen, Christian Franke, www.smartmontools.org
+
+=== START OF INFORMATION SECTION ===
+Model Family:     Western Digital Red
+Device Model:     WDC WD40EFRX-68N32N0
+Serial Number:    WD-WCC7K1234567
+Firmware Version: 82.00A82
+User Capacity:    4,000,787,030,016 bytes [4.00 TB]
+
+=== START OF READ SMART DATA SECTION ===
+SMART overall-health self-assessment test result: PASSED
+
+SMART Attributes Data Structure revision number: 16
+Vendor Specific SMART Attributes with Thresholds:
+ID# ATTRIBUTE_NAME          FLAG     VALUE WORST THRESH TYPE      UPDATED  WHEN_FAILED RAW_VALUE
+  1 Raw_Read_Error_Rate     0x002f   200   200   051    Pre-fail  Always       -       0
+  9 Power_On_Hours          0x0032   078   078   000    Old_age   Always       -       16213
+ 12 Power_Cycle_Count       0x0032   100   100   000    Old_age   Always       -       57
+194 Temperature_Celsius     0x0022   114   104   000    Old_age   Always       -       36 (Min/Max 20/46)
+`
+
+func TestParsePlaintext(t *testing.T) {
+	json := parsePlaintext(Device{Name: "/dev/sda", Info_Name: "sda", Type: "sat"}, plaintextATAOutput, 0)
+	for path, want := range map[string]string{
+		"model_name":                                "WDC WD40EFRX-68N32N0",
+		"model_family":                              "Western Digital Red",
+		"serial_number":                             "WD-WCC7K1234567",
+		"smart_status.passed":                       "true",
+		"temperature.current":                       "36",
+		"power_on_time.hours":                       "16213",
+		"power_cycle_count":                         "57",
+		"smartctl.version":                          "[6,6]",
+		"ata_smart_attributes.table.#":              "4",
+		"ata_smart_attributes.table.0.thresh":       "51",
+		"ata_smart_attributes.table.0.flags.string": "POSR-K",
+		"ata_smart_attributes.table.3.raw.string":   "36 (Min/Max 20/46)",
+	} {
+		if got := json.Get(path).String(); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+}
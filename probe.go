@@ -0,0 +1,81 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/tidwall/gjson"
+)
+
+// deviceAccess holds whether the devices could be opened by the startup
+// probe, by device
+var deviceAccess sync.Map
+
+var (
+	permissionDeniedRegexp = regexp.MustCompile(`(?i)permission denied|operation not permitted`)
+	deviceTypeHintRegexp   = regexp.MustCompile(`(?i)unknown usb bridge|please specify device type`)
+)
+
+// smartctlMessages joins the messages smartctl reported
+func smartctlMessages(json gjson.Result) string {
+	var messages []string
+	for _, message := range json.Get("smartctl.messages").Array() {
+		messages = append(messages, message.Get("string").String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// deviceAccessHint returns a hint why the device could not be opened, empty
+// if it was opened
+func deviceAccessHint(json gjson.Result) string {
+	exitStatus := json.Get("smartctl.exit_status")
+	messages := smartctlMessages(json)
+	switch {
+	case !exitStatus.Exists():
+		return "smartctl produced no json output, check smartctl.path and run smartctl --info on the device"
+	case exitStatus.Int()&(exitCommandLineError|exitDeviceOpenFailed) == 0 || deviceInStandby(json):
+		return ""
+	case permissionDeniedRegexp.MatchString(messages):
+		return "run the exporter as root or with the CAP_SYS_RAWIO and CAP_SYS_ADMIN capabilities"
+	case exitStatus.Int()&exitCommandLineError != 0:
+		return "smartctl rejected the device type, check smartctl.device-type"
+	case deviceTypeHintRegexp.MatchString(messages):
+		return "smartctl cannot detect the device type, set it with smartctl.device-type"
+	default:
+		return "check the smartctl messages"
+	}
+}
+
+// probeDeviceAccess opens every device with smartctl --info before the
+// exporter serves metrics, logging those failing with a hint
+func probeDeviceAccess(logger log.Logger, devices []Device) {
+	failed := 0
+	for _, device := range devices {
+		out, _, _ := runSMARTctl("--json", "--info", "--nocheck=standby", "--device="+device.Type, device.Name)
+		json := parseJSON(string(out), jsonSourceReal)
+		hint := deviceAccessHint(json)
+		deviceAccess.Store(device, hint == "")
+		if hint == "" {
+			continue
+		}
+		failed++
+		level.Warn(logger).Log("msg", "Device is not accessible", "device", device.Info_Name, "type", device.Type, "hint", hint, "messages", smartctlMessages(json), "exit_status", json.Get("smartctl.exit_status").Int())
+	}
+	level.Info(logger).Log("msg", "Probed device access", "devices", len(devices), "inaccessible", failed)
+}
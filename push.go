@@ -0,0 +1,43 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// PushMetrics periodically pushes the collected metrics to a Pushgateway
+func PushMetrics(logger log.Logger, gatherer prometheus.Gatherer, url string, job string, interval time.Duration) {
+	instance, err := os.Hostname()
+	if err != nil {
+		level.Warn(logger).Log("msg", "Unable to determine hostname for the instance label", "err", err)
+	}
+	pusher := push.New(url, job).
+		Grouping("instance", instance).
+		Gatherer(gatherer)
+	for {
+		if err := pusher.Push(); err != nil {
+			level.Error(logger).Log("msg", "Pushing metrics to Pushgateway failed", "url", url, "err", err)
+		} else {
+			level.Debug(logger).Log("msg", "Pushed metrics to Pushgateway", "url", url)
+		}
+		time.Sleep(interval)
+	}
+}
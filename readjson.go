@@ -14,101 +14,949 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tidwall/gjson"
 )
 
 // JSONCache caching json
 type JSONCache struct {
 	JSON        gjson.Result
+	Previous    gjson.Result
 	LastCollect time.Time
+	// Exit status of the last smartctl run, successful or not
+	ExitStatus int64
+	// Device labels extracted from JSON when storing it, so scrapes between
+	// polls do not extract them again
+	Labels *SMARTDevice
+	// Difference between the exporter clock and the local_time reported by
+	// smartctl when storing the entry
+	ClockSkew time.Duration
+	// Last output of smartctl reporting the device in a low-power mode
+	Standby     gjson.Result
+	LastStandby time.Time
+	// Whether the entry was restored from the cache snapshot and not polled
+	// since
+	Restored bool
 }
 
+// Sources of smartctl json
+const (
+	jsonSourceReal = "real"
+	jsonSourceFake = "fake"
+)
+
 var (
 	jsonCache sync.Map
+	// devicesWithoutSMART holds the last json of the devices found not to
+	// support SMART
+	devicesWithoutSMART sync.Map
+
+	errOutputTooLarge = errors.New("smartctl output exceeds the maximum size")
+
+	jsonOutputOversized = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smartctl_output_oversized_total",
+			Help: "Number of smartctl outputs rejected for exceeding the maximum output size",
+		},
+		[]string{"source"},
+	)
+	devicesSkippedFresh = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "smartctl_devices_skipped_fresh_total",
+			Help: "Number of times a device was not polled as its cached data was younger than the poll interval",
+		},
+	)
+	devicesNeededTypeFallback = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "smartctl_devices_needed_type_fallback",
+			Help: "Number of devices found by the last scan whose device type had to be probed, consider configuring their type",
+		},
+	)
+	duplicateDevices = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "smartctl_duplicate_devices_detected",
+			Help: "Number of devices the last scan found more than once with the same device type, which are polled only once",
+		},
+	)
+	// pollsInProgress counts the smartctl device polls currently running
+	pollsInProgress atomic.Int64
+	devicesPolling  = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "smartctl_devices_polling_in_progress",
+			Help: "Number of devices smartctl is currently running for",
+		},
+		func() float64 { return float64(pollsInProgress.Load()) },
+	)
+	jsonParseInvalid = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smartctl_json_parse_invalid_total",
+			Help: "Number of smartctl outputs that were not valid json and were replaced by an empty object",
+		},
+		[]string{"source"},
+	)
 )
 
 func init() {
-	jsonCache.Store("", JSONCache{})
+	jsonParseInvalid.WithLabelValues(jsonSourceReal)
+	jsonParseInvalid.WithLabelValues(jsonSourceFake)
+	jsonOutputOversized.WithLabelValues(jsonSourceReal)
+	jsonOutputOversized.WithLabelValues(jsonSourceFake)
 }
 
 // Parse json to gjson object
-func parseJSON(data string) gjson.Result {
+func parseJSON(data string, source string) gjson.Result {
 	if !gjson.Valid(data) {
+		// Empty output is a smartctl failure, which is reported elsewhere
+		if data != "" {
+			jsonParseInvalid.WithLabelValues(source).Inc()
+		}
 		return gjson.Parse("{}")
 	}
 	return gjson.Parse(data)
 }
 
+// fakeDataDir is the directory holding the fake smartctl json
+var fakeDataDir = "debug"
+
+var fakeFilenameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fakeFilenames returns the fake json filenames of the device, by
+// preference. The device path without /dev/ and the type identify the
+// device, e.g. bus_0_megaraid_0.json for /dev/bus/0 of type megaraid,0. The
+// path alone and the last path element, as used before, are the fallbacks.
+func fakeFilenames(device Device) []string {
+	path := fakeFilenameRegexp.ReplaceAllString(strings.TrimPrefix(device.Name, "/dev/"), "_")
+	names := []string{path}
+	if device.Type != "" {
+		names = append([]string{deviceFilename(device)}, names...)
+	}
+	s := strings.Split(device.Name, "/")
+	if legacy := s[len(s)-1]; legacy != path {
+		names = append(names, legacy)
+	}
+	for i, name := range names {
+		names[i] = filepath.Join(fakeDataDir, name+".json")
+	}
+	return names
+}
+
+// deviceFilename returns the name identifying the device in file names, the
+// device path without /dev/ and the type with other characters than letters,
+// digits, ".", "_" and "-" replaced
+func deviceFilename(device Device) string {
+	name := fakeFilenameRegexp.ReplaceAllString(strings.TrimPrefix(device.Name, "/dev/"), "_")
+	if device.Type == "" {
+		return name
+	}
+	return name + "_" + fakeFilenameRegexp.ReplaceAllString(device.Type, "_")
+}
+
+// jsonDumps holds the time the json of each device was last dumped
+var jsonDumps sync.Map
+
+// dumpJSON writes the json of the device to the dump directory, at most once
+// per dump interval. The files are named like the fake data files, so the
+// directory can be used with smartctl.fake-data.
+func dumpJSON(logger log.Logger, device Device, json gjson.Result) {
+	now := time.Now()
+	if last, ok := jsonDumps.Load(device); ok && now.Sub(last.(time.Time)) < *smartctlDumpJSONInterval {
+		return
+	}
+	jsonDumps.Store(device, now)
+	filename := filepath.Join(*smartctlDumpJSONDir, deviceFilename(device)+".json")
+	tmp, err := os.CreateTemp(*smartctlDumpJSONDir, deviceFilename(device)+".*")
+	if err == nil {
+		_, err = tmp.WriteString(json.Raw)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), filename)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		level.Warn(logger).Log("msg", "Dumping smartctl json failed", "device", device.Info_Name, "file", filename, "err", err)
+		return
+	}
+	level.Debug(logger).Log("msg", "Dumped smartctl json", "device", device.Info_Name, "file", filename)
+}
+
 // Reading fake smartctl json
 func readFakeSMARTctl(logger log.Logger, device Device) gjson.Result {
-	s := strings.Split(device.Name, "/")
-	filename := fmt.Sprintf("debug/%s.json", s[len(s)-1])
+	if fakeScenario != nil {
+		return readScenarioFile(logger, fakeScenario, scenarioFiles[device])
+	}
+	filenames := fakeFilenames(device)
+	for _, filename := range filenames {
+		if _, err := os.Stat(filename); err == nil {
+			return readFakeSMARTctlFile(logger, filename)
+		}
+	}
+	return readFakeSMARTctlFile(logger, filenames[0])
+}
+
+// Reading smartctl json from a file
+func readFakeSMARTctlFile(logger log.Logger, filename string) gjson.Result {
 	level.Debug(logger).Log("msg", "Read fake S.M.A.R.T. data from json", "filename", filename)
-	jsonFile, err := os.ReadFile(filename)
+	jsonFile, err := readFileCapped(filename, *smartctlMaxOutputBytes)
+	if err == errOutputTooLarge {
+		jsonOutputOversized.WithLabelValues(jsonSourceFake).Inc()
+	}
 	if err != nil {
 		level.Error(logger).Log("msg", "Fake S.M.A.R.T. data reading error", "err", err)
-		return parseJSON("{}")
+		return parseJSON("{}", jsonSourceFake)
+	}
+	return parseJSON(string(jsonFile), jsonSourceFake)
+}
+
+// readFileCapped reads a file, failing if it is larger than limit bytes. A
+// limit of 0 disables the check.
+func readFileCapped(filename string, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return os.ReadFile(filename)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errOutputTooLarge
+	}
+	return data, nil
+}
+
+// cappedBuffer keeps at most limit bytes written to it and discards the rest,
+// so that a pathological smartctl output does not have to be held in memory.
+// A limit of 0 disables the cap.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && int64(b.Len()+len(p)) > b.limit {
+		b.overflow = true
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// Time to wait for the output of a killed smartctl before giving up on it
+const smartctlWaitDelay = 5 * time.Second
+
+// ionice(1) scheduling classes by name
+var ioniceClasses = map[string]string{
+	"realtime":    "1",
+	"best-effort": "2",
+	"idle":        "3",
+}
+
+// smartctlCommand builds the smartctl command, prefixed with ionice and nice
+// when they are configured
+func smartctlCommand(ctx context.Context, args ...string) *exec.Cmd {
+	var argv []string
+	if *smartctlIoniceClass != "" {
+		argv = append(argv, "ionice", "-c", ioniceClasses[*smartctlIoniceClass])
+	}
+	if *smartctlNice != 0 {
+		argv = append(argv, "nice", "-n", strconv.Itoa(*smartctlNice))
+	}
+	argv = append(argv, expandCommandWrapper(commandWrapper, *smartctlPath, args)...)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = commandEnv
+	setProcessGroup(cmd)
+	// Do not wait forever for output pipes held open by stray children
+	cmd.WaitDelay = smartctlWaitDelay
+	return cmd
+}
+
+// commandEnv holds the environment smartctl runs with, nil inheriting the
+// environment of the exporter
+var commandEnv []string
+
+// parseCommandEnv validates the KEY=VALUE pairs and merges them into the
+// inherited environment, unless clean is set
+func parseCommandEnv(pairs []string, clean bool) ([]string, error) {
+	for _, pair := range pairs {
+		if key, _, found := strings.Cut(pair, "="); !found || key == "" {
+			return nil, fmt.Errorf("invalid environment variable %q, expected KEY=VALUE", pair)
+		}
+	}
+	if clean {
+		// An empty, non-nil environment keeps exec from inheriting
+		return append([]string{}, pairs...), nil
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	// Later values win over inherited ones of the same key
+	return append(os.Environ(), pairs...), nil
+}
+
+// commandWrapper holds the tokenized smartctl.command-wrapper template
+var commandWrapper []string
+
+// parseCommandWrapper splits a command template into words like a shell
+// would, honouring single and double quotes and backslash escapes. No other
+// shell syntax is interpreted, the words are passed to exec as they are. A
+// template must contain {smartctl}, smartctl.path would be ignored otherwise.
+func parseCommandWrapper(template string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range template {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("unterminated quote or escape in command template %q", template)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	if len(words) > 0 && !slices.ContainsFunc(words, func(word string) bool {
+		return strings.Contains(word, "{smartctl}")
+	}) {
+		return nil, fmt.Errorf("command template %q does not contain {smartctl}", template)
+	}
+	return words, nil
+}
+
+// expandCommandWrapper substitutes the {smartctl} and {args} words of the
+// wrapper. The arguments are appended if there is no {args} word, and
+// without a wrapper smartctl is run directly.
+func expandCommandWrapper(wrapper []string, path string, args []string) []string {
+	if len(wrapper) == 0 {
+		return append([]string{path}, args...)
+	}
+	var argv []string
+	expanded := false
+	for _, word := range wrapper {
+		switch word {
+		case "{args}":
+			argv = append(argv, args...)
+			expanded = true
+		default:
+			argv = append(argv, strings.ReplaceAll(word, "{smartctl}", path))
+		}
+	}
+	if !expanded {
+		argv = append(argv, args...)
+	}
+	return argv
+}
+
+// Run smartctl and return its stdout and stderr
+func runSMARTctl(args ...string) ([]byte, string, error) {
+	ctx := context.Background()
+	if *smartctlTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *smartctlTimeout)
+		defer cancel()
+	}
+	var stderr bytes.Buffer
+	stdout := cappedBuffer{limit: *smartctlMaxOutputBytes}
+	cmd := smartctlCommand(ctx, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if *smartctlCgroup != "" {
+		cgroup, err := setCgroup(cmd, *smartctlCgroup)
+		if err != nil {
+			return nil, "", err
+		}
+		defer cgroup.Close()
+	}
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("smartctl timed out after %s: %w", *smartctlTimeout, err)
+	}
+	if stdout.overflow {
+		jsonOutputOversized.WithLabelValues(jsonSourceReal).Inc()
+		return nil, strings.TrimSpace(stderr.String()), errOutputTooLarge
+	}
+	return stdout.Bytes(), strings.TrimSpace(stderr.String()), err
+}
+
+// validateCommandWrappers checks that the configured nice/ionice tools,
+// command wrapper and cgroup are usable
+func validateCommandWrappers() error {
+	if *smartctlNice < -20 || *smartctlNice > 19 {
+		return fmt.Errorf("niceness %d out of range [-20, 19]", *smartctlNice)
+	}
+	if *smartctlNice != 0 {
+		if _, err := exec.LookPath("nice"); err != nil {
+			return err
+		}
+	}
+	if *smartctlIoniceClass != "" {
+		if _, err := exec.LookPath("ionice"); err != nil {
+			return err
+		}
+	}
+	if len(commandWrapper) > 0 {
+		if _, err := exec.LookPath(commandWrapper[0]); err != nil {
+			return err
+		}
+	}
+	if *smartctlCgroup != "" {
+		return validateCgroup(*smartctlCgroup)
+	}
+	return nil
+}
+
+// smartctlResolveDuration is the time resolving the smartctl binary took at
+// startup
+var smartctlResolveDuration time.Duration
+
+// resolveSMARTctl checks at startup that the smartctl binary, or the command
+// wrapper running it, can be found, recording how long the lookup took
+func resolveSMARTctl(logger log.Logger) {
+	binary := *smartctlPath
+	if len(commandWrapper) > 0 {
+		binary = commandWrapper[0]
+	}
+	start := time.Now()
+	path, err := exec.LookPath(binary)
+	smartctlResolveDuration = time.Since(start)
+	if err != nil {
+		level.Warn(logger).Log("msg", "smartctl binary not found", "path", binary, "err", err, "duration", smartctlResolveDuration)
+		return
+	}
+	level.Debug(logger).Log("msg", "Resolved smartctl binary", "path", path, "duration", smartctlResolveDuration)
+}
+
+// ignoredExitBits holds the smartctl exit status bits treated as success
+var ignoredExitBits int64
+
+// parseExitBits parses a comma separated list of exit status bit numbers
+// into a mask
+func parseExitBits(list string) (int64, error) {
+	var mask int64
+	for _, bit := range strings.Split(list, ",") {
+		bit = strings.TrimSpace(bit)
+		if bit == "" {
+			continue
+		}
+		number, err := strconv.ParseUint(bit, 10, 8)
+		if err != nil || number > 7 {
+			return 0, fmt.Errorf("invalid exit status bit %q, expected 0-7", bit)
+		}
+		mask |= 1 << number
+	}
+	return mask, nil
+}
+
+// logDirectoryAddresses holds the ATA log addresses exported from the log
+// directory
+var logDirectoryAddresses []uint64
+
+// parseLogAddresses parses a comma separated list of hexadecimal log addresses
+func parseLogAddresses(list string) ([]uint64, error) {
+	var addresses []uint64
+	for _, address := range strings.Split(list, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToLower(address), "0x") {
+			return nil, fmt.Errorf("log address %q is not hexadecimal", address)
+		}
+		value, err := strconv.ParseUint(address[2:], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log address %q: %w", address, err)
+		}
+		addresses = append(addresses, value)
+	}
+	return addresses, nil
+}
+
+// deviceTypeArgs returns the --get and --log arguments supported by the
+// device type only
+func deviceTypeArgs(device Device) []string {
+	switch device.Type {
+	case "nvme":
+		return nil
+	case "scsi":
+		return []string{"--get=wcache", "--log=background", "--log=sasphy"}
+	default:
+		return []string{"--get=wcache", "--get=lookahead", "--get=apm", "--get=aam"}
+	}
+}
+
+// pollArgs returns the smartctl arguments to poll the device with
+func pollArgs(device Device) []string {
+	if *smartctlMinimal {
+		// smartctl reports the temperatures only along with the attributes
+		return []string{"--json", "--health", "--attributes", "--nocheck=standby", "--device=" + device.Type, device.Name}
+	}
+	args := []string{"--json", "--info", "--health", "--attributes", "--capabilities", "--tolerance=verypermissive", "--nocheck=standby", "--format=brief", "--log=error", "--log=selftest"}
+	if collectGroups["xerror"] {
+		args = append(args, "--log=xerror")
+	}
+	if *smartctlVendorLogs {
+		args = append(args, "--log=farm")
+	}
+	if len(logDirectoryAddresses) > 0 {
+		args = append(args, "--log=directory")
+	}
+	args = append(args, deviceTypeArgs(device)...)
+	return append(args, "--device="+device.Type, device.Name)
+}
+
+// deviceCounters counts the poll events of a device
+type deviceCounters struct {
+	pollRetries  atomic.Uint64
+	emptyOutputs atomic.Uint64
+}
+
+// pollCounters holds the deviceCounters of the scanned devices
+var pollCounters sync.Map
+
+func countersOf(device Device) *deviceCounters {
+	counters, _ := pollCounters.LoadOrStore(device, &deviceCounters{})
+	return counters.(*deviceCounters)
+}
+
+// syncDeviceState creates the counters of the scanned devices, which are
+// exported from the first scrape on, and drops the state kept for the devices
+// a rescan no longer found, so that their series and poll histories disappear
+// with the device
+func syncDeviceState(devices []Device) {
+	for _, device := range devices {
+		countersOf(device)
 	}
-	return parseJSON(string(jsonFile))
+	for _, state := range []*sync.Map{&pollCounters, &pollLocks, &pollHistories} {
+		state.Range(func(key, _ any) bool {
+			if !slices.Contains(devices, key.(Device)) {
+				state.Delete(key)
+			}
+			return true
+		})
+	}
+	// The life baselines are keyed like the monotonic counters
+	keys := map[string]bool{}
+	for _, device := range devices {
+		keys[device.Name+";"+device.Type] = true
+	}
+	lifeBaselines.Range(func(key, _ any) bool {
+		if !keys[key.(string)] {
+			lifeBaselines.Delete(key)
+		}
+		return true
+	})
 }
 
 // Get json from smartctl and parse it
 func readSMARTctl(logger log.Logger, device Device) (gjson.Result, bool) {
+	json, ok := readSMARTctlOnce(logger, device)
+	for retry := 0; retry < *smartctlRetries && !ok && pollIsRetryable(json); retry++ {
+		level.Debug(logger).Log("msg", "Retrying smartctl", "device", device.Info_Name, "retry", retry+1)
+		countersOf(device).pollRetries.Add(1)
+		time.Sleep(*smartctlRetryDelay)
+		json, ok = readSMARTctlOnce(logger, device)
+	}
+	return json, ok
+}
+
+// pollIsRetryable reports whether a failed poll may succeed when repeated,
+// which is not the case for sleeping devices and rejected command lines
+func pollIsRetryable(json gjson.Result) bool {
+	return !deviceInStandby(json) && !deviceWithoutSMART(json) && json.Get("smartctl.exit_status").Int()&exitCommandLineError == 0
+}
+
+func readSMARTctlOnce(logger log.Logger, device Device) (gjson.Result, bool) {
+	pollsInProgress.Add(1)
+	defer pollsInProgress.Add(-1)
+	if plaintextMode {
+		return readSMARTctlPlaintext(logger, device)
+	}
 	start := time.Now()
-	out, err := exec.Command(*smartctlPath, "--json", "--info", "--health", "--attributes", "--tolerance=verypermissive", "--nocheck=standby", "--format=brief", "--log=error", "--device="+device.Type, device.Name).Output()
+	out, stderr, err := runSMARTctl(pollArgs(device)...)
+	if err == nil && len(bytes.TrimSpace(out)) == 0 {
+		level.Warn(logger).Log("msg", "smartctl succeeded without output, check its permissions", "device", device.Info_Name, "stderr", stderr)
+		countersOf(device).emptyOutputs.Add(1)
+		// The empty object it parses to would pass as healthy otherwise
+		recordPoll(device, start, 0, false)
+		return gjson.Parse("{}"), false
+	}
+	json := parseJSON(string(out), jsonSourceReal)
+	// With --nocheck=standby smartctl exits with 2 for sleeping devices,
+	// this is expected and not a failure.
+	if deviceInStandby(json) {
+		level.Debug(logger).Log("msg", "Device is in a low-power mode, skipping", "device", device.Info_Name)
+		recordPoll(device, start, json.Get("smartctl.exit_status").Int(), false)
+		return json, false
+	}
+	// Devices without SMART are reported, but not cached as a good poll
+	if deviceWithoutSMART(json) {
+		if _, known := devicesWithoutSMART.Swap(device, json); !known {
+			level.Info(logger).Log("msg", "Device does not support SMART", "device", device.Info_Name)
+		}
+		recordPoll(device, start, json.Get("smartctl.exit_status").Int(), false)
+		return json, false
+	}
 	if err != nil {
-		level.Warn(logger).Log("msg", "S.M.A.R.T. output reading", "err", err, "device", device.Info_Name)
+		level.Warn(logger).Log("msg", "S.M.A.R.T. output reading", "err", err, "stderr", stderr, "device", device.Info_Name)
+	} else if stderr != "" {
+		level.Debug(logger).Log("msg", "S.M.A.R.T. stderr output", "stderr", stderr, "device", device.Info_Name)
 	}
-	json := parseJSON(string(out))
-	rcOk := resultCodeIsOk(logger, device, json.Get("smartctl.exit_status").Int())
+	// Ignored bits are only masked for the cache decision, metrics report the
+	// exit status as is
+	rcOk := resultCodeIsOk(logger, device, json.Get("smartctl.exit_status").Int()&^ignoredExitBits)
 	jsonOk := jsonIsOk(logger, json)
+	recordPoll(device, start, json.Get("smartctl.exit_status").Int(), rcOk && jsonOk)
 	level.Debug(logger).Log("msg", "Collected S.M.A.R.T. json data", "device", device.Info_Name, "duration", time.Since(start))
 	return json, rcOk && jsonOk
 }
 
 func readSMARTctlDevices(logger log.Logger) gjson.Result {
+	if plaintextMode {
+		return readSMARTctlPlaintextDevices(logger)
+	}
 	level.Debug(logger).Log("msg", "Scanning for devices")
-	out, err := exec.Command(*smartctlPath, "--json", "--scan").Output()
+	out, stderr, err := runSMARTctl("--json", "--scan")
 	if exiterr, ok := err.(*exec.ExitError); ok {
 		level.Debug(logger).Log("msg", "Exit Status", "exit_code", exiterr.ExitCode())
 		// The smartctl command returns 2 if devices are sleeping, ignore this error.
 		if exiterr.ExitCode() != 2 {
-			level.Warn(logger).Log("msg", "S.M.A.R.T. output reading error", "err", err)
+			level.Warn(logger).Log("msg", "S.M.A.R.T. output reading error", "err", err, "stderr", stderr)
 			return gjson.Result{}
 		}
+	} else if err != nil {
+		level.Warn(logger).Log("msg", "S.M.A.R.T. output reading error", "err", err)
+		return gjson.Result{}
 	}
-	return parseJSON(string(out))
+	return parseJSON(string(out), jsonSourceReal)
+}
+
+// deviceTypeCandidates are the device types probed, in order, for devices
+// configured with the auto type
+var deviceTypeCandidates = []string{"sat", "scsi", "nvme", "ata"}
+
+// probedDeviceTypes caches the device type found by probing, by device name
+var probedDeviceTypes sync.Map
+
+// probeDeviceType returns the first candidate device type smartctl reports
+// SMART data with for the device
+func probeDeviceType(logger log.Logger, name string) (string, bool) {
+	if deviceType, ok := probedDeviceTypes.Load(name); ok {
+		return deviceType.(string), true
+	}
+	for _, candidate := range deviceTypeCandidates {
+		// Sleeping devices fail every candidate, they are probed again by
+		// the next rescan instead of being spun up
+		out, _, _ := runSMARTctl("--json", "--info", "--health", "--nocheck=standby", "--device="+candidate, name)
+		json := parseJSON(string(out), jsonSourceReal)
+		exitStatus := json.Get("smartctl.exit_status").Int()
+		if exitStatus&(exitCommandLineError|exitDeviceOpenFailed) != 0 || !json.Get("smart_status").Exists() {
+			level.Debug(logger).Log("msg", "Device type does not work for device", "name", name, "type", candidate, "exit_status", exitStatus)
+			continue
+		}
+		level.Info(logger).Log("msg", "Probed device type", "name", name, "type", candidate)
+		probedDeviceTypes.Store(name, candidate)
+		return candidate, true
+	}
+	return "", false
+}
+
+// pollLocks holds a mutex per device, so that concurrent collections, e.g.
+// of a scrape and a push, do not run smartctl for the same device twice
+var pollLocks sync.Map
+
+// pollData reads the data of the device, waiting for a poll of the device
+// still running for another collection
+func pollData(logger log.Logger, device Device, interval time.Duration) JSONCache {
+	lock, _ := pollLocks.LoadOrStore(device, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+	return readData(logger, device, interval)
 }
 
 // Select json source and parse
-func readData(logger log.Logger, device Device) gjson.Result {
+func readData(logger log.Logger, device Device, interval time.Duration) JSONCache {
 	if *smartctlFakeData {
-		return readFakeSMARTctl(logger, device)
+		json := readFakeSMARTctl(logger, device)
+		return JSONCache{JSON: json, ExitStatus: json.Get("smartctl.exit_status").Int()}
+	}
+
+	if device.Type == smartdDeviceType {
+		return readSmartdData(logger, device)
 	}
 
 	cacheValue, cacheOk := jsonCache.Load(device)
-	if !cacheOk || time.Now().After(cacheValue.(JSONCache).LastCollect.Add(*smartctlInterval)) {
+	if pollingPaused.Load() {
+		if cacheOk {
+			return cacheValue.(JSONCache)
+		}
+		return JSONCache{}
+	}
+	if *smartctlExcludeNoSMART {
+		if json, ok := devicesWithoutSMART.Load(device); ok {
+			return JSONCache{JSON: json.(gjson.Result), ExitStatus: json.(gjson.Result).Get("smartctl.exit_status").Int()}
+		}
+	}
+	interval = pollInterval(device, interval)
+	if cacheOk && *smartctlNeverWake > 0 && time.Since(cacheValue.(JSONCache).LastStandby) < *smartctlNeverWake {
+		level.Debug(logger).Log("msg", "Device was recently in a low-power mode, not polling", "device", device.Info_Name)
+		standby := cacheValue.(JSONCache).Standby
+		return JSONCache{JSON: standby, ExitStatus: standby.Get("smartctl.exit_status").Int()}
+	}
+	if !cacheOk || time.Now().After(cacheValue.(JSONCache).LastCollect.Add(interval)) {
 		json, ok := readSMARTctl(logger, device)
+		exitStatus := json.Get("smartctl.exit_status").Int()
 		if ok {
-			jsonCache.Store(device, JSONCache{JSON: json, LastCollect: time.Now()})
+			if *smartctlDumpJSONDir != "" {
+				dumpJSON(logger, device, json)
+			}
+			var previous gjson.Result
+			if cacheOk {
+				previous = cacheValue.(JSONCache).JSON
+			}
+			labels := newSMARTDevice(json)
+			now := time.Now()
+			jsonCache.Store(device, JSONCache{
+				JSON:        json,
+				Previous:    previous,
+				LastCollect: now,
+				ExitStatus:  exitStatus,
+				Labels:      &labels,
+				ClockSkew:   now.Sub(time.Unix(json.Get("local_time.time_t").Int(), 0)),
+			})
 			j, found := jsonCache.Load(device)
 			if !found {
 				level.Warn(logger).Log("msg", "device not found", "device", device.Info_Name)
 			}
-			return j.(JSONCache).JSON
+			return j.(JSONCache)
 		}
-		return gjson.Result{}
+		// Keep the last good data cached, only report the power mode
+		if deviceInStandby(json) {
+			var entry JSONCache
+			if cacheOk {
+				entry = cacheValue.(JSONCache)
+			}
+			entry.Standby = json
+			entry.LastStandby = time.Now()
+			entry.ExitStatus = exitStatus
+			jsonCache.Store(device, entry)
+			return JSONCache{JSON: json, ExitStatus: exitStatus}
+		}
+		if deviceWithoutSMART(json) {
+			return JSONCache{JSON: json, ExitStatus: exitStatus}
+		}
+		return JSONCache{ExitStatus: exitStatus}
+	}
+	devicesSkippedFresh.Inc()
+	return cacheValue.(JSONCache)
+}
+
+// readSmartdData reads the state smartd stored for the device, using the
+// modification time of the state file as the time of the last poll
+func readSmartdData(logger log.Logger, device Device) JSONCache {
+	info, err := os.Stat(device.Name)
+	if err != nil {
+		level.Error(logger).Log("msg", "Reading smartd state failed", "path", device.Name, "err", err)
+		return JSONCache{}
+	}
+	cacheValue, cacheOk := jsonCache.Load(device)
+	if cacheOk && cacheValue.(JSONCache).LastCollect.Equal(info.ModTime()) {
+		return cacheValue.(JSONCache)
+	}
+	json, ok := readSmartdState(logger, device)
+	if !ok {
+		return JSONCache{}
+	}
+	labels := newSMARTDevice(json)
+	// The names of smartd states are no device paths, they are used as
+	// they are
+	if labels.device == "" {
+		labels.device = device.Info_Name
+		labels.alias = lookupAlias(device.Name, device.Info_Name)
+	}
+	entry := JSONCache{JSON: json, LastCollect: info.ModTime(), Labels: &labels}
+	if cacheOk {
+		entry.Previous = cacheValue.(JSONCache).JSON
+	}
+	jsonCache.Store(device, entry)
+	return entry
+}
+
+var (
+	standbyMessageRegexp = regexp.MustCompile(`^Device is in (\S+) mode`)
+	// SCSI devices refusing a command in a low-power condition report it
+	// with the sense data, e.g. "Standby_z condition activated by timer"
+	standbySCSISenseRegexp = regexp.MustCompile(`(?i)\b((?:idle|standby)(?:_[a-z])?) condition activated by`)
+)
+
+// deviceInStandby reports whether smartctl skipped the device because it was
+// in a low-power mode
+func deviceInStandby(json gjson.Result) bool {
+	return standbyMode(json) != ""
+}
+
+// standbyMode returns the low-power mode reported by smartctl, if any. The
+// SCSI power conditions IDLE_A to IDLE_C and STANDBY_Y and STANDBY_Z are
+// reported as IDLE and STANDBY, like the ATA power modes.
+func standbyMode(json gjson.Result) string {
+	if json.Get("smartctl.exit_status").Int()&exitDeviceOpenFailed == 0 {
+		return ""
+	}
+	for _, message := range json.Get("smartctl.messages").Array() {
+		match := standbyMessageRegexp.FindStringSubmatch(message.Get("string").String())
+		if match == nil && json.Get("device.protocol").String() == "SCSI" {
+			match = standbySCSISenseRegexp.FindStringSubmatch(message.Get("string").String())
+		}
+		if match != nil {
+			mode, _, _ := strings.Cut(strings.ToUpper(match[1]), "_")
+			return mode
+		}
+	}
+	return ""
+}
+
+var openFailedMessageRegexp = regexp.MustCompile(`open device: .* failed`)
+
+var (
+	// virtualDevicePathRegexp matches the paths of block devices that are no
+	// disks and have no SMART
+	virtualDevicePathRegexp = regexp.MustCompile(`^/dev/(loop|ram|zram|nbd)[0-9]+$`)
+	// virtualDeviceModelRegexp matches the models of emulated disks
+	virtualDeviceModelRegexp = regexp.MustCompile(`(?i)^(QEMU|VMware|VBOX|Virtual)\b`)
+)
+
+// deviceWithoutSMART reports whether the device does not provide SMART data:
+// smartctl reports no SMART support, or a known virtual device returned no
+// SMART data
+func deviceWithoutSMART(json gjson.Result) bool {
+	if deviceInStandby(json) {
+		return false
+	}
+	if available := json.Get("smart_support.available"); available.Exists() {
+		return !available.Bool()
+	}
+	if json.Get("smartctl.exit_status").Int()&exitDeviceOpenFailed == 0 {
+		return false
+	}
+	for _, message := range json.Get("smartctl.messages").Array() {
+		if openFailedMessageRegexp.MatchString(message.Get("string").String()) {
+			return false
+		}
+	}
+	if virtualDevicePathRegexp.MatchString(json.Get("device.name").String()) {
+		return true
+	}
+	for _, path := range []string{"model_name", "scsi_model_name", "scsi_vendor"} {
+		if virtualDeviceModelRegexp.MatchString(strings.TrimSpace(json.Get(path).String())) {
+			return true
+		}
+	}
+	return false
+}
+
+// pollInterval returns the interval between polls of the device, based on
+// its cached data
+func pollInterval(device Device, interval time.Duration) time.Duration {
+	if cacheValue, cacheOk := jsonCache.Load(device); cacheOk {
+		return deviceInterval(cacheValue.(JSONCache).JSON, interval)
+	}
+	return interval
+}
+
+// deviceInterval clamps the poll interval of rotational devices to the
+// configured minimum
+func deviceInterval(json gjson.Result, interval time.Duration) time.Duration {
+	if interval < *smartctlMinInterval && json.Get("rotation_rate").Int() > 0 {
+		return *smartctlMinInterval
+	}
+	return interval
+}
+
+// smartctl exit status bits, see smartctl(8)
+const (
+	exitCommandLineError      = 1 << 0
+	exitDeviceOpenFailed      = 1 << 1
+	exitCommandFailed         = 1 << 2
+	exitDiskFailing           = 1 << 3
+	exitPrefailBelowThreshold = 1 << 4
+	exitPastBelowThreshold    = 1 << 5
+	exitErrorLog              = 1 << 6
+	exitSelfTestErrors        = 1 << 7
+)
+
+// Device health summarized from smartctl output
+const (
+	healthUnknown = iota
+	healthPassed
+	healthWarning
+	healthFailing
+)
+
+// deviceHealth summarizes the health reported by smartctl: failing when the
+// SMART status check failed, warning when non-fatal problems were found
+func deviceHealth(json gjson.Result) int {
+	if !json.Get("smart_status").Exists() {
+		return healthUnknown
+	}
+	exitStatus := json.Get("smartctl.exit_status").Int()
+	if !json.Get("smart_status.passed").Bool() || exitStatus&exitDiskFailing != 0 {
+		return healthFailing
+	}
+	if exitStatus&(exitCommandFailed|exitPrefailBelowThreshold|exitPastBelowThreshold|exitErrorLog|exitSelfTestErrors) != 0 {
+		return healthWarning
 	}
-	return cacheValue.(JSONCache).JSON
+	return healthPassed
 }
 
 // Parse smartctl return code
@@ -116,30 +964,30 @@ func resultCodeIsOk(logger log.Logger, device Device, SMARTCtlResult int64) bool
 	result := true
 	if SMARTCtlResult > 0 {
 		b := SMARTCtlResult
-		if (b & 1) != 0 {
+		if (b & exitCommandLineError) != 0 {
 			level.Error(logger).Log("msg", "Command line did not parse", "device", device.Info_Name)
 			result = false
 		}
-		if (b & (1 << 1)) != 0 {
+		if (b & exitDeviceOpenFailed) != 0 {
 			level.Error(logger).Log("msg", "Device open failed, device did not return an IDENTIFY DEVICE structure, or device is in a low-power mode", "device", device.Info_Name)
 			result = false
 		}
-		if (b & (1 << 2)) != 0 {
+		if (b & exitCommandFailed) != 0 {
 			level.Warn(logger).Log("msg", "Some SMART or other ATA command to the disk failed, or there was a checksum error in a SMART data structure", "device", device.Info_Name)
 		}
-		if (b & (1 << 3)) != 0 {
+		if (b & exitDiskFailing) != 0 {
 			level.Warn(logger).Log("msg", "SMART status check returned 'DISK FAILING'", "device", device.Info_Name)
 		}
-		if (b & (1 << 4)) != 0 {
+		if (b & exitPrefailBelowThreshold) != 0 {
 			level.Warn(logger).Log("msg", "We found prefail Attributes <= threshold", "device", device.Info_Name)
 		}
-		if (b & (1 << 5)) != 0 {
+		if (b & exitPastBelowThreshold) != 0 {
 			level.Warn(logger).Log("msg", "SMART status check returned 'DISK OK' but we found that some (usage or prefail) Attributes have been <= threshold at some time in the past", "device", device.Info_Name)
 		}
-		if (b & (1 << 6)) != 0 {
+		if (b & exitErrorLog) != 0 {
 			level.Warn(logger).Log("msg", "The device error log contains records of errors", "device", device.Info_Name)
 		}
-		if (b & (1 << 7)) != 0 {
+		if (b & exitSelfTestErrors) != 0 {
 			level.Warn(logger).Log("msg", "The device self-test log contains records of errors. [ATA only] Failed self-tests outdated by a newer successful extended self-test are ignored", "device", device.Info_Name)
 		}
 	}
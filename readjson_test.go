@@ -0,0 +1,156 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/tidwall/gjson"
+)
+
+func TestCommandWrapper(t *testing.T) {
+	args := []string{"--json", "/dev/sda; rm -rf /"}
+	tests := []struct {
+		template string
+		want     []string
+	}{
+		{
+			template: "",
+			want:     []string{"/usr/sbin/smartctl", "--json", "/dev/sda; rm -rf /"},
+		},
+		{
+			template: "nsenter --target 1 --mount -- {smartctl} {args}",
+			want:     []string{"nsenter", "--target", "1", "--mount", "--", "/usr/sbin/smartctl", "--json", "/dev/sda; rm -rf /"},
+		},
+		{
+			template: `kubectl exec "smart sidecar" -- {smartctl}`,
+			want:     []string{"kubectl", "exec", "smart sidecar", "--", "/usr/sbin/smartctl", "--json", "/dev/sda; rm -rf /"},
+		},
+		{
+			template: `sh -c 'echo $0' {args} {smartctl}\ x`,
+			want:     []string{"sh", "-c", "echo $0", "--json", "/dev/sda; rm -rf /", "/usr/sbin/smartctl x"},
+		},
+	}
+	for _, test := range tests {
+		wrapper, err := parseCommandWrapper(test.template)
+		if err != nil {
+			t.Fatalf("%q: %v", test.template, err)
+		}
+		if got := expandCommandWrapper(wrapper, "/usr/sbin/smartctl", args); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %q, want %q", test.template, got, test.want)
+		}
+	}
+
+	for _, template := range []string{`nsenter "--target`, `nsenter 'x`, `nsenter \`, `nsenter --target 1 -- smartctl {args}`} {
+		if _, err := parseCommandWrapper(template); err == nil {
+			t.Errorf("%q was accepted", template)
+		}
+	}
+}
+
+func TestFakeFilenames(t *testing.T) {
+	fakeDataDir = "testdata/fake"
+	defer func() { fakeDataDir = "debug" }()
+
+	tests := []struct {
+		device Device
+		serial string
+	}{
+		{Device{Name: "/dev/sda", Type: "sat+megaraid,0"}, "MEGARAID0"},
+		{Device{Name: "/dev/sda", Type: "sat+megaraid,1"}, "MEGARAID1"},
+		{Device{Name: "/dev/nvme0n1", Type: "nvme"}, "NVME0N1"},
+	}
+	for _, test := range tests {
+		json := readFakeSMARTctl(log.NewNopLogger(), test.device)
+		if serial := json.Get("serial_number").String(); serial != test.serial {
+			t.Errorf("%+v: serial_number = %q, want %q", test.device, serial, test.serial)
+		}
+	}
+
+	want := []string{"testdata/fake/bus_0_megaraid_0.json", "testdata/fake/bus_0.json", "testdata/fake/0.json"}
+	if got := fakeFilenames(Device{Name: "/dev/bus/0", Type: "megaraid,0"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("fakeFilenames = %q, want %q", got, want)
+	}
+}
+
+func TestStandbyMode(t *testing.T) {
+	tests := []struct {
+		filename string
+		mode     string
+	}{
+		{"testdata/scsi_standby_z.json", "STANDBY"},
+		{"testdata/scsi_standby_sense.json", "STANDBY"},
+		{"testdata/HITACHI_H109060SESUN600G_9.json", ""},
+	}
+	for _, test := range tests {
+		json := readFakeSMARTctlFile(log.NewNopLogger(), test.filename)
+		if mode := standbyMode(json); mode != test.mode {
+			t.Errorf("%s: mode = %q, want %q", test.filename, mode, test.mode)
+		}
+	}
+}
+
+func TestDeviceWithoutSMART(t *testing.T) {
+	tests := []struct {
+		filename string
+		noSMART  bool
+	}{
+		{"testdata/QEMU_HARDDISK_1.json", true},
+		{"testdata/scsi_standby_z.json", false},
+		{"testdata/HITACHI_H109060SESUN600G_9.json", false},
+	}
+	for _, test := range tests {
+		json := readFakeSMARTctlFile(log.NewNopLogger(), test.filename)
+		if noSMART := deviceWithoutSMART(json); noSMART != test.noSMART {
+			t.Errorf("%s: deviceWithoutSMART = %v, want %v", test.filename, noSMART, test.noSMART)
+		}
+	}
+
+	loop := gjson.Parse(`{"smartctl": {"exit_status": 2}, "device": {"name": "/dev/loop0"}}`)
+	if !deviceWithoutSMART(loop) {
+		t.Error("device without identity is not reported")
+	}
+	unknown := gjson.Parse(`{"smartctl": {"exit_status": 2}, "device": {"name": "/dev/sdb"}}`)
+	if deviceWithoutSMART(unknown) {
+		t.Error("disk without identity is reported")
+	}
+	vm := gjson.Parse(`{"smartctl": {"exit_status": 2}, "device": {"name": "/dev/sdc"}, "scsi_vendor": "VMware"}`)
+	if !deviceWithoutSMART(vm) {
+		t.Error("virtual disk without SMART data is not reported")
+	}
+	gone := gjson.Parse(`{"smartctl": {"exit_status": 2, "messages": [{"string": "Smartctl open device: /dev/sdx failed: No such device"}]}}`)
+	if deviceWithoutSMART(gone) {
+		t.Error("device failing to open is reported")
+	}
+}
+
+func TestDeviceAccessHint(t *testing.T) {
+	tests := []struct {
+		json       string
+		accessible bool
+	}{
+		{`{"smartctl": {"exit_status": 0}}`, true},
+		{`{"smartctl": {"exit_status": 4}}`, true},
+		{`{"smartctl": {"exit_status": 2, "messages": [{"string": "Smartctl open device: /dev/sda failed: Permission denied"}]}}`, false},
+		{`{"smartctl": {"exit_status": 1}}`, false},
+		{`{}`, false},
+	}
+	for _, test := range tests {
+		if hint := deviceAccessHint(gjson.Parse(test.json)); (hint == "") != test.accessible {
+			t.Errorf("%s: got hint %q", test.json, hint)
+		}
+	}
+}
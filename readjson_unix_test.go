@@ -0,0 +1,156 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestRunSMARTctlTimeoutKillsChildren(t *testing.T) {
+	// A fake smartctl that spawns a long running child holding stdout open.
+	fake := filepath.Join(t.TempDir(), "smartctl")
+	script := "#!/bin/sh\nsleep 60 &\nwait\n"
+	if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldPath, oldTimeout := *smartctlPath, *smartctlTimeout
+	defer func() {
+		*smartctlPath, *smartctlTimeout = oldPath, oldTimeout
+	}()
+	*smartctlPath = fake
+	*smartctlTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	_, _, err := runSMARTctl("--json", "--scan")
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Errorf("expected an error from a timed out smartctl")
+	}
+	// Killing only the direct child would leave the pipe open until the
+	// wait delay expires.
+	if elapsed >= smartctlWaitDelay {
+		t.Errorf("smartctl was not killed promptly, took %s", elapsed)
+	}
+}
+
+func TestJSONCacheHoldsOnlyDevices(t *testing.T) {
+	fixture, err := filepath.Abs("testdata/Hitachi_HUA722020ALA330_29.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := filepath.Join(t.TempDir(), "smartctl")
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\ncat "+fixture+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oldPath := *smartctlPath
+	defer func() {
+		*smartctlPath = oldPath
+	}()
+	*smartctlPath = fake
+
+	devices := []Device{
+		{Name: "/dev/sda", Info_Name: "sda", Type: "sat"},
+		{Name: "/dev/sdb", Info_Name: "sdb", Type: "sat"},
+	}
+	for _, device := range devices {
+		if data := readData(log.NewNopLogger(), device, time.Minute); !data.JSON.Exists() {
+			t.Fatalf("no data read for %s", device.Name)
+		}
+		defer jsonCache.Delete(device)
+	}
+
+	jsonCache.Range(func(key, value any) bool {
+		device, ok := key.(Device)
+		if !ok || !slices.Contains(devices, device) {
+			t.Errorf("unexpected cache key %#v", key)
+		}
+		return true
+	})
+}
+
+func TestCommandEnv(t *testing.T) {
+	// A fake smartctl printing a variable set by smartctl.env and one
+	// inherited from the exporter
+	fake := filepath.Join(t.TempDir(), "smartctl")
+	script := "#!/bin/sh\necho \"$VENDOR_LICENSE:$INHERITED\"\n"
+	if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("INHERITED", "yes")
+
+	oldPath, oldEnv := *smartctlPath, commandEnv
+	defer func() {
+		*smartctlPath, commandEnv = oldPath, oldEnv
+	}()
+	*smartctlPath = fake
+
+	for clean, want := range map[bool]string{false: "key:yes\n", true: "key:\n"} {
+		env, err := parseCommandEnv([]string{"VENDOR_LICENSE=key"}, clean)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commandEnv = env
+		out, _, err := runSMARTctl("--scan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != want {
+			t.Errorf("clean=%v: output %q, want %q", clean, out, want)
+		}
+	}
+
+	if _, err := parseCommandEnv([]string{"=value"}, false); err == nil {
+		t.Error("variable without key was accepted")
+	}
+}
+
+func TestEmptyOutput(t *testing.T) {
+	fake := filepath.Join(t.TempDir(), "smartctl")
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	oldPath := *smartctlPath
+	defer func() {
+		*smartctlPath = oldPath
+	}()
+	*smartctlPath = fake
+
+	device := Device{Name: "/dev/sdz", Info_Name: "sdz", Type: "sat"}
+	defer syncDeviceState(nil)
+	if _, ok := readSMARTctlOnce(log.NewNopLogger(), device); ok {
+		t.Error("empty output accepted as a successful poll")
+	}
+	if count := countersOf(device).emptyOutputs.Load(); count != 1 {
+		t.Errorf("smartctl_device_empty_output_total = %v, want 1", count)
+	}
+
+	// Every scrape exports the count before polling the device again
+	reg := newCollectorRegistry(t, []Device{device}, false)
+	for scrape := 1; scrape <= 2; scrape++ {
+		family := gatherFamily(t, reg, "smartctl_device_empty_output_total")
+		if family == nil || family.GetMetric()[0].GetCounter().GetValue() != float64(scrape) {
+			t.Errorf("scrape %d: smartctl_device_empty_output_total = %v", scrape, family)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gopkg.in/yaml.v2"
+)
+
+// relabelConfig is a relabeling rule, following the Prometheus
+// relabel_config semantics for the supported actions
+type relabelConfig struct {
+	SourceLabels []string `yaml:"source_labels"`
+	Separator    *string  `yaml:"separator"`
+	Regex        *string  `yaml:"regex"`
+	TargetLabel  string   `yaml:"target_label"`
+	Replacement  *string  `yaml:"replacement"`
+	Action       string   `yaml:"action"`
+}
+
+type relabelFile struct {
+	RelabelConfigs []relabelConfig `yaml:"relabel_configs"`
+}
+
+// labelNameRegexp matches the valid label names
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type relabelRule struct {
+	sourceLabels []string
+	separator    string
+	regex        *regexp.Regexp
+	targetLabel  string
+	replacement  string
+	action       string
+}
+
+// loadRelabelRules reads the relabeling rules from a yaml file
+func loadRelabelRules(filename string) ([]relabelRule, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file relabelFile
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, err
+	}
+	var rules []relabelRule
+	for i, config := range file.RelabelConfigs {
+		rule, err := newRelabelRule(config)
+		if err != nil {
+			return nil, fmt.Errorf("relabel rule %d: %w", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func newRelabelRule(config relabelConfig) (relabelRule, error) {
+	rule := relabelRule{
+		sourceLabels: config.SourceLabels,
+		separator:    ";",
+		targetLabel:  config.TargetLabel,
+		replacement:  "$1",
+		action:       config.Action,
+	}
+	if config.Separator != nil {
+		rule.separator = *config.Separator
+	}
+	if config.Replacement != nil {
+		rule.replacement = *config.Replacement
+	}
+	if rule.action == "" {
+		rule.action = "replace"
+	}
+	expr := "(.*)"
+	if config.Regex != nil {
+		expr = *config.Regex
+	}
+	regex, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return rule, err
+	}
+	rule.regex = regex
+
+	switch rule.action {
+	case "replace":
+		if rule.targetLabel == "" {
+			return rule, fmt.Errorf("action %s requires target_label", rule.action)
+		}
+	case "keep", "drop":
+		if len(rule.sourceLabels) == 0 {
+			return rule, fmt.Errorf("action %s requires source_labels", rule.action)
+		}
+	case "labeldrop", "labelkeep", "labelmap":
+	default:
+		return rule, fmt.Errorf("unsupported action %q", rule.action)
+	}
+	if rule.targetLabel == "__name__" {
+		return rule, fmt.Errorf("the metric name cannot be relabeled")
+	}
+	if rule.targetLabel != "" && !labelNameRegexp.MatchString(rule.targetLabel) {
+		return rule, fmt.Errorf("invalid target_label %q", rule.targetLabel)
+	}
+	return rule, nil
+}
+
+// relabel applies the rules to the labels, the metric name being available
+// as __name__. It returns false if the metric is to be dropped.
+func relabel(rules []relabelRule, name string, labels map[string]string) bool {
+	for _, rule := range rules {
+		values := make([]string, len(rule.sourceLabels))
+		for i, label := range rule.sourceLabels {
+			if label == "__name__" {
+				values[i] = name
+			} else {
+				values[i] = labels[label]
+			}
+		}
+		value := strings.Join(values, rule.separator)
+
+		switch rule.action {
+		case "replace":
+			match := rule.regex.FindStringSubmatchIndex(value)
+			if match == nil {
+				continue
+			}
+			target := string(rule.regex.ExpandString(nil, rule.replacement, value, match))
+			if target == "" {
+				delete(labels, rule.targetLabel)
+			} else {
+				labels[rule.targetLabel] = target
+			}
+		case "keep":
+			if !rule.regex.MatchString(value) {
+				return false
+			}
+		case "drop":
+			if rule.regex.MatchString(value) {
+				return false
+			}
+		case "labeldrop", "labelkeep":
+			for label := range labels {
+				if rule.regex.MatchString(label) == (rule.action == "labeldrop") {
+					delete(labels, label)
+				}
+			}
+		case "labelmap":
+			// The mapped labels are added after matching all the present
+			// ones, so that they are not mapped again
+			mapped := map[string]string{}
+			for label, labelValue := range labels {
+				if !rule.regex.MatchString(label) {
+					continue
+				}
+				target := rule.regex.ReplaceAllString(label, rule.replacement)
+				if target != "__name__" && labelNameRegexp.MatchString(target) {
+					mapped[target] = labelValue
+				}
+			}
+			for label, labelValue := range mapped {
+				labels[label] = labelValue
+			}
+		}
+	}
+	return true
+}
+
+// relabelGatherer applies relabeling rules to the gathered metrics. Rules
+// removing the labels distinguishing series produce duplicates and are up to
+// the user to avoid.
+type relabelGatherer struct {
+	gatherer prometheus.Gatherer
+	rules    []relabelRule
+}
+
+// newRelabelGatherer wraps the gatherer, if there are any rules
+func newRelabelGatherer(gatherer prometheus.Gatherer, rules []relabelRule) prometheus.Gatherer {
+	if len(rules) == 0 {
+		return gatherer
+	}
+	return relabelGatherer{gatherer: gatherer, rules: rules}
+}
+
+// Gather implements prometheus.Gatherer
+func (g relabelGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	var result []*dto.MetricFamily
+	for _, family := range families {
+		var metrics []*dto.Metric
+		for _, metric := range family.Metric {
+			labels := map[string]string{}
+			for _, pair := range metric.Label {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			if !relabel(g.rules, family.GetName(), labels) {
+				continue
+			}
+			metric.Label = metric.Label[:0]
+			for name, value := range labels {
+				metric.Label = append(metric.Label, &dto.LabelPair{Name: stringPtr(name), Value: stringPtr(value)})
+			}
+			sort.Slice(metric.Label, func(i, j int) bool {
+				return metric.Label[i].GetName() < metric.Label[j].GetName()
+			})
+			metrics = append(metrics, metric)
+		}
+		if len(metrics) > 0 {
+			family.Metric = metrics
+			result = append(result, family)
+		}
+	}
+	return result, err
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,191 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func mustRelabelRule(t *testing.T, config relabelConfig) relabelRule {
+	t.Helper()
+	rule, err := newRelabelRule(config)
+	if err != nil {
+		t.Fatalf("invalid rule %+v: %v", config, err)
+	}
+	return rule
+}
+
+func TestRelabel(t *testing.T) {
+	regex := func(s string) *string { return &s }
+	tests := []struct {
+		name   string
+		config relabelConfig
+		labels map[string]string
+		want   map[string]string
+		keep   bool
+	}{
+		{
+			name: "replace strips vendor prefix",
+			config: relabelConfig{
+				SourceLabels: []string{"model_name"},
+				Regex:        regex("(?:WDC|ATA) (.*)"),
+				TargetLabel:  "model_name",
+			},
+			labels: map[string]string{"model_name": "WDC WD40EFRX"},
+			want:   map[string]string{"model_name": "WD40EFRX"},
+			keep:   true,
+		},
+		{
+			name: "replace without match keeps labels",
+			config: relabelConfig{
+				SourceLabels: []string{"model_name"},
+				Regex:        regex("WDC (.*)"),
+				TargetLabel:  "model_name",
+			},
+			labels: map[string]string{"model_name": "ST4000NM"},
+			want:   map[string]string{"model_name": "ST4000NM"},
+			keep:   true,
+		},
+		{
+			name: "replace joins source labels",
+			config: relabelConfig{
+				SourceLabels: []string{"device", "type"},
+				TargetLabel:  "disk",
+			},
+			labels: map[string]string{"device": "sda", "type": "sat"},
+			want:   map[string]string{"device": "sda", "type": "sat", "disk": "sda;sat"},
+			keep:   true,
+		},
+		{
+			name: "drop by metric name",
+			config: relabelConfig{
+				SourceLabels: []string{"__name__"},
+				Regex:        regex("smartctl_device_attribute.*"),
+				Action:       "drop",
+			},
+			labels: map[string]string{"device": "sda"},
+			keep:   false,
+		},
+		{
+			name: "keep non-matching drops",
+			config: relabelConfig{
+				SourceLabels: []string{"device"},
+				Regex:        regex("nvme.*"),
+				Action:       "keep",
+			},
+			labels: map[string]string{"device": "sda"},
+			keep:   false,
+		},
+		{
+			name: "labeldrop",
+			config: relabelConfig{
+				Regex:  regex("alias|type"),
+				Action: "labeldrop",
+			},
+			labels: map[string]string{"device": "sda", "alias": "", "type": "sat"},
+			want:   map[string]string{"device": "sda"},
+			keep:   true,
+		},
+		{
+			name: "labelmap renames",
+			config: relabelConfig{
+				Regex:       regex("device"),
+				Replacement: regex("disk"),
+				Action:      "labelmap",
+			},
+			labels: map[string]string{"device": "sda"},
+			want:   map[string]string{"device": "sda", "disk": "sda"},
+			keep:   true,
+		},
+		{
+			name: "labelmap maps every label once",
+			config: relabelConfig{
+				Regex:       regex("(.*)"),
+				Replacement: regex("disk_$1"),
+				Action:      "labelmap",
+			},
+			labels: map[string]string{"device": "sda", "type": "sat"},
+			want:   map[string]string{"device": "sda", "type": "sat", "disk_device": "sda", "disk_type": "sat"},
+			keep:   true,
+		},
+		{
+			name: "labelmap skips invalid names",
+			config: relabelConfig{
+				Regex:       regex("device"),
+				Replacement: regex("disk-$0"),
+				Action:      "labelmap",
+			},
+			labels: map[string]string{"device": "sda"},
+			want:   map[string]string{"device": "sda"},
+			keep:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules := []relabelRule{mustRelabelRule(t, test.config)}
+			keep := relabel(rules, "smartctl_device_attribute", test.labels)
+			if keep != test.keep {
+				t.Fatalf("keep = %v, want %v", keep, test.keep)
+			}
+			if keep && !reflect.DeepEqual(test.labels, test.want) {
+				t.Errorf("labels = %v, want %v", test.labels, test.want)
+			}
+		})
+	}
+}
+
+func TestRelabelRuleValidation(t *testing.T) {
+	for _, config := range []relabelConfig{
+		{Action: "replace"},
+		{Action: "drop"},
+		{Action: "hashmod"},
+		{Action: "replace", TargetLabel: "__name__"},
+		{Action: "replace", TargetLabel: "disk-name"},
+	} {
+		if _, err := newRelabelRule(config); err == nil {
+			t.Errorf("rule %+v was accepted", config)
+		}
+	}
+}
+
+func TestRelabelGatherer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_gauge"}, []string{"device", "model_name"})
+	gauge.WithLabelValues("sda", "WDC WD40EFRX").Set(1)
+	gauge.WithLabelValues("sdb", "ST4000NM").Set(1)
+	reg.MustRegister(gauge)
+
+	rules := []relabelRule{
+		mustRelabelRule(t, relabelConfig{SourceLabels: []string{"device"}, Regex: stringPtr("sdb"), Action: "drop"}),
+		mustRelabelRule(t, relabelConfig{SourceLabels: []string{"model_name"}, TargetLabel: "disk"}),
+		mustRelabelRule(t, relabelConfig{Regex: stringPtr("model_name"), Action: "labeldrop"}),
+	}
+	families, err := newRelabelGatherer(reg, rules).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 1 || len(families[0].Metric) != 1 {
+		t.Fatalf("unexpected families %v", families)
+	}
+	var names []string
+	for _, pair := range families[0].Metric[0].Label {
+		names = append(names, pair.GetName()+"="+pair.GetValue())
+	}
+	if want := []string{"device=sda", "disk=WDC WD40EFRX"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("labels = %v, want %v", names, want)
+	}
+}
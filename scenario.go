@@ -0,0 +1,104 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/tidwall/gjson"
+)
+
+// builtinScenarios holds the fake data scenarios shipped with the exporter,
+// one directory of smartctl json per scenario
+//
+//go:embed scenarios
+var builtinScenarios embed.FS
+
+// fakeScenario holds the smartctl json of the selected fake data scenario,
+// nil if none is selected
+var fakeScenario fs.FS
+
+// scenarioFiles maps the devices of the scenario to their json file
+var scenarioFiles = map[Device]string{}
+
+// builtinScenarioNames returns the names of the built-in scenarios
+func builtinScenarioNames() []string {
+	entries, _ := builtinScenarios.ReadDir("scenarios")
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadFakeScenario opens a scenario, a directory of smartctl json files or
+// the name of a built-in scenario
+func loadFakeScenario(name string) (fs.FS, error) {
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		return os.DirFS(name), nil
+	}
+	if !fs.ValidPath(name) || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("unknown scenario %q, must be a directory or one of: %s", name, strings.Join(builtinScenarioNames(), ", "))
+	}
+	if _, err := fs.Stat(builtinScenarios, path.Join("scenarios", name)); err != nil {
+		return nil, fmt.Errorf("unknown scenario %q, must be a directory or one of: %s", name, strings.Join(builtinScenarioNames(), ", "))
+	}
+	return fs.Sub(builtinScenarios, path.Join("scenarios", name))
+}
+
+// scanScenarioDevices registers a device for every json file of the
+// scenario, as described by its device object
+func scanScenarioDevices(logger log.Logger, scenario fs.FS) []Device {
+	filenames, err := fs.Glob(scenario, "*.json")
+	if err != nil {
+		level.Error(logger).Log("msg", "Reading the fake data scenario failed", "err", err)
+		return nil
+	}
+	var devices []Device
+	for _, filename := range filenames {
+		json := readScenarioFile(logger, scenario, filename)
+		device := Device{
+			Name:      json.Get("device.name").String(),
+			Info_Name: extractDiskName(strings.TrimSpace(json.Get("device.info_name").String())),
+			Type:      json.Get("device.type").String(),
+		}
+		if device.Name == "" {
+			level.Warn(logger).Log("msg", "Ignoring scenario file without device", "file", filename)
+			continue
+		}
+		level.Info(logger).Log("msg", "Found scenario device", "name", device.Info_Name, "file", filename)
+		scenarioFiles[device] = filename
+		devices = append(devices, device)
+	}
+	return devices
+}
+
+// readScenarioFile reads a smartctl json file of the scenario
+func readScenarioFile(logger log.Logger, scenario fs.FS, filename string) gjson.Result {
+	data, err := fs.ReadFile(scenario, filename)
+	if err != nil {
+		level.Error(logger).Log("msg", "Fake S.M.A.R.T. data reading error", "file", filename, "err", err)
+		return parseJSON("{}", jsonSourceFake)
+	}
+	return parseJSON(string(data), jsonSourceFake)
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestBuiltinScenarios(t *testing.T) {
+	defer func() { scenarioFiles = map[Device]string{} }()
+	names := builtinScenarioNames()
+	if len(names) == 0 {
+		t.Fatal("no built-in scenarios")
+	}
+	for _, name := range names {
+		scenario, err := loadFakeScenario(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		devices := scanScenarioDevices(log.NewNopLogger(), scenario)
+		if len(devices) == 0 {
+			t.Errorf("%s: no devices", name)
+		}
+		for _, device := range devices {
+			json := readScenarioFile(log.NewNopLogger(), scenario, scenarioFiles[device])
+			if json.Get("device.name").String() != device.Name {
+				t.Errorf("%s: json of %s does not match the device", name, device.Name)
+			}
+		}
+	}
+
+	for _, name := range []string{"bogus", "../testdata", "healthy/sda.json"} {
+		if _, err := loadFakeScenario(name); err == nil {
+			t.Errorf("scenario %q was accepted", name)
+		}
+	}
+}
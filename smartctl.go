@@ -15,8 +15,15 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -27,6 +34,8 @@ import (
 // SMARTDevice - short info about device
 type SMARTDevice struct {
 	device string
+	path   string
+	alias  string
 	serial string
 	family string
 	model  string
@@ -39,10 +48,193 @@ type SMARTDevice struct {
 type SMARTctl struct {
 	ch     chan<- prometheus.Metric
 	json   gjson.Result
+	cache  JSONCache
 	logger log.Logger
 	device SMARTDevice
 }
 
+// ataAttributeFlags lists the flags of ATA SMART attributes
+var ataAttributeFlags = []string{
+	"prefailure",
+	"updated_online",
+	"performance",
+	"error_rate",
+	"event_count",
+	"auto_keep",
+}
+
+// ataDeltaAttributes lists the ATA SMART attributes whose raw value change
+// between polls is exported, as their growth hints at an imminent failure
+var ataDeltaAttributes = []int64{
+	5,   // Reallocated_Sector_Ct
+	187, // Reported_Uncorrect
+	188, // Command_Timeout
+	197, // Current_Pending_Sector
+	198, // Offline_Uncorrectable
+	199, // UDMA_CRC_Error_Count
+}
+
+// metricGroups lists the metric groups selectable with --smartctl.collect
+var metricGroups = []string{
+	"info",
+	"health",
+	"temperature",
+	"attributes",
+	"statistics",
+	"errorlog",
+	"nvme",
+	"scsi",
+	"vendor",
+	"xerror",
+}
+
+// defaultMetricGroups lists the metric groups collected by default. The
+// extended ATA error log enlarges the smartctl json considerably and is
+// opt-in.
+var defaultMetricGroups = slices.DeleteFunc(slices.Clone(metricGroups), func(group string) bool {
+	return group == "xerror"
+})
+
+// collectGroups holds the enabled metric groups
+var collectGroups = map[string]bool{}
+
+// parseCollectGroups parses a comma separated list of metric groups
+func parseCollectGroups(list string) (map[string]bool, error) {
+	groups := map[string]bool{}
+	for _, group := range strings.Split(list, ",") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		if !slices.Contains(metricGroups, group) {
+			return nil, fmt.Errorf("unknown metric group %q, must be one of: %s", group, strings.Join(metricGroups, ", "))
+		}
+		groups[group] = true
+	}
+	return groups, nil
+}
+
+// rawStringAttributes holds the ATA SMART attribute IDs whose raw string is
+// exported
+var rawStringAttributes []int64
+
+// parseAttributeIDs parses a comma separated list of ATA SMART attribute IDs
+func parseAttributeIDs(list string) ([]int64, error) {
+	var ids []int64
+	for _, id := range strings.Split(list, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		number, err := strconv.ParseUint(id, 10, 8)
+		if err != nil || number == 0 {
+			return nil, fmt.Errorf("invalid attribute ID %q, expected 1-255", id)
+		}
+		ids = append(ids, int64(number))
+	}
+	return ids, nil
+}
+
+// criticalAttributes holds the ATA SMART attribute IDs the health score is
+// derived from
+var criticalAttributes []int64
+
+// attributeNominalValues are the normalized values vendors start attributes
+// at, the score of an attribute is relative to the one it started at
+var attributeNominalValues = []int64{100, 200, 253}
+
+// healthScore returns the score from 0 to 100 of the critical attributes.
+// Every attribute scores the distance of its normalized value to the
+// threshold, relative to that of its nominal value: 100 at the nominal
+// value, 0 at or below the threshold. The lowest score is the device's,
+// as a single failing attribute fails the device. Attributes with a threshold
+// at or above every nominal value are skipped.
+func healthScore(json gjson.Result, ids []int64) (float64, bool) {
+	score, found := 100.0, false
+	for _, attribute := range json.Get("ata_smart_attributes.table").Array() {
+		if !slices.Contains(ids, attribute.Get("id").Int()) {
+			continue
+		}
+		value, thresh := attribute.Get("value").Int(), attribute.Get("thresh").Int()
+		nominal := attributeNominalValues[len(attributeNominalValues)-1]
+		for _, candidate := range attributeNominalValues {
+			if candidate >= value && candidate > thresh {
+				nominal = candidate
+				break
+			}
+		}
+		// Without a range above the threshold there is nothing to score
+		if nominal <= thresh {
+			continue
+		}
+		attributeScore := 100 * float64(value-thresh) / float64(nominal-thresh)
+		score = math.Min(score, math.Max(0, math.Min(100, attributeScore)))
+		found = true
+	}
+	return score, found
+}
+
+func (smart *SMARTctl) mineHealthScore() {
+	score, ok := healthScore(smart.json, criticalAttributes)
+	if !ok {
+		return
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceHealthScore,
+		prometheus.GaugeValue,
+		score,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+// deviceAliases maps device paths or names to operator defined aliases
+var deviceAliases = map[string]string{}
+
+// parseDeviceAliases parses a list of DEVICE=ALIAS pairs
+func parseDeviceAliases(pairs []string) (map[string]string, error) {
+	aliases := map[string]string{}
+	for _, pair := range pairs {
+		device, alias, found := strings.Cut(pair, "=")
+		if !found || device == "" {
+			return nil, fmt.Errorf("expected DEVICE=ALIAS, got %q", pair)
+		}
+		aliases[device] = alias
+	}
+	return aliases, nil
+}
+
+// deviceIdentity returns a stable identity of the device, its WWN if known or
+// its serial number otherwise
+func deviceIdentity(json gjson.Result) string {
+	if wwn := json.Get("wwn"); wwn.Exists() {
+		return fmt.Sprintf("0x%016x", wwn.Get("naa").Uint()<<60|wwn.Get("oui").Uint()<<36|wwn.Get("id").Uint())
+	}
+	return strings.TrimSpace(json.Get("serial_number").String())
+}
+
+// deviceLabel returns the device label of the metrics exported for a scanned
+// device outside of its smartctl output, matching that of newSMARTDevice
+func deviceLabel(device Device) string {
+	if *smartctlOmitDevicePath {
+		if cached, ok := jsonCache.Load(device); ok {
+			if identity := deviceIdentity(cached.(JSONCache).JSON); identity != "" {
+				return identity
+			}
+		}
+	}
+	return device.Info_Name
+}
+
+// lookupAlias returns the alias of a device by its path or name
+func lookupAlias(path string, name string) string {
+	if alias, ok := deviceAliases[path]; ok {
+		return alias
+	}
+	return deviceAliases[name]
+}
+
 func extractDiskName(input string) string {
 	re := regexp.MustCompile(`^(?:/dev/(?P<bus_name>\S+)/(?P<bus_num>\S+)\s\[|/dev/|\[)(?:\s\[|)(?P<disk>[a-z0-9_]+)(?:\].*|)$`)
 	match := re.FindStringSubmatch(input)
@@ -69,6 +261,33 @@ func extractDiskName(input string) string {
 
 // NewSMARTctl is smartctl constructor
 func NewSMARTctl(logger log.Logger, json gjson.Result, ch chan<- prometheus.Metric) SMARTctl {
+	return SMARTctl{
+		ch:     ch,
+		json:   json,
+		logger: logger,
+		device: newSMARTDevice(json),
+	}
+}
+
+// NewSMARTctlFromCache is smartctl constructor reusing the device labels
+// computed when the cache entry was stored
+func NewSMARTctlFromCache(logger log.Logger, cache JSONCache, ch chan<- prometheus.Metric) SMARTctl {
+	if cache.Labels == nil {
+		smart := NewSMARTctl(logger, cache.JSON, ch)
+		smart.SetCache(cache)
+		return smart
+	}
+	return SMARTctl{
+		ch:     ch,
+		json:   cache.JSON,
+		cache:  cache,
+		logger: logger,
+		device: *cache.Labels,
+	}
+}
+
+// newSMARTDevice extracts the device labels from the json
+func newSMARTDevice(json gjson.Result) SMARTDevice {
 	var model_name string
 	if obj := json.Get("model_name"); obj.Exists() {
 		model_name = obj.String()
@@ -80,66 +299,273 @@ func NewSMARTctl(logger log.Logger, json gjson.Result, ch chan<- prometheus.Metr
 		model_name = "unknown"
 	}
 
-	return SMARTctl{
-		ch:     ch,
-		json:   json,
-		logger: logger,
-		device: SMARTDevice{
-			device:     extractDiskName(strings.TrimSpace(json.Get("device.info_name").String())),
-			serial:     strings.TrimSpace(json.Get("serial_number").String()),
-			family:     strings.TrimSpace(GetStringIfExists(json, "model_family", "unknown")),
-			model:      strings.TrimSpace(model_name),
-			interface_: strings.TrimSpace(json.Get("device.type").String()),
-			protocol:   strings.TrimSpace(json.Get("device.protocol").String()),
-		},
+	path := extractDiskName(strings.TrimSpace(json.Get("device.info_name").String()))
+	alias := lookupAlias(json.Get("device.name").String(), path)
+	device := path
+	if *smartctlOmitDevicePath {
+		if identity := deviceIdentity(json); identity != "" {
+			device = identity
+		}
 	}
+
+	return SMARTDevice{
+		device:     device,
+		path:       path,
+		alias:      alias,
+		serial:     strings.TrimSpace(json.Get("serial_number").String()),
+		family:     strings.TrimSpace(GetStringIfExists(json, "model_family", "unknown")),
+		model:      strings.TrimSpace(model_name),
+		interface_: strings.TrimSpace(json.Get("device.type").String()),
+		protocol:   strings.TrimSpace(json.Get("device.protocol").String()),
+	}
+}
+
+// SetCache sets the cache entry the json was read from, used for the data
+// age and the deltas to the previous poll
+func (smart *SMARTctl) SetCache(cache JSONCache) {
+	smart.cache = cache
 }
 
 // Collect metrics
 func (smart *SMARTctl) Collect() {
 	level.Debug(smart.logger).Log("msg", "Collecting metrics from", "device", smart.device.device, "family", smart.device.family, "model", smart.device.model)
-	smart.mineExitStatus()
-	smart.mineDevice()
-	smart.mineCapacity()
-	smart.mineBlockSize()
-	smart.mineInterfaceSpeed()
-	smart.mineDeviceAttribute()
-	smart.minePowerOnSeconds()
-	smart.mineRotationRate()
-	smart.mineTemperatures()
-	smart.minePowerCycleCount() // ATA/SATA, NVME, SCSI, SAS
-	smart.mineDeviceSCTStatus()
-	smart.mineDeviceStatistics()
-	smart.mineDeviceErrorLog()
-	smart.mineDeviceSelfTestLog()
-	smart.mineDeviceERC()
-	smart.mineSmartStatus()
-
-	if smart.device.interface_ == "nvme" {
+	if *smartctlMinimal {
+		if standbyMode(smart.json) == "" && !deviceWithoutSMART(smart.json) {
+			smart.mineSmartStatus()
+			smart.mineTemperatures()
+		}
+		return
+	}
+	if mode := standbyMode(smart.json); mode != "" {
+		smart.mineStandby(mode)
+		return
+	}
+	noSMART := deviceWithoutSMART(smart.json)
+	smart.mineNoSMARTSupport(noSMART)
+	smart.mineSMARTSupport()
+	if noSMART {
+		smart.mineExitStatus()
+		return
+	}
+	if collectGroups["info"] {
+		smart.mineExitStatus()
+		smart.mineDataAge()
+		smart.mineDataRestored()
+		smart.mineJSONSize()
+		smart.mineClockSkew()
+		smart.mineDevice()
+		smart.mineDevicePath()
+		smart.mineDeviceNumber()
+		smart.mineDeviceController()
+		smart.mineATAVersion()
+		smart.mineCacheState()
+		smart.mineATAPowerManagement()
+		smart.mineCapacity()
+		smart.mineBlockSize()
+		smart.mineSectorEmulation()
+		smart.mineTrim()
+		smart.mineZoned()
+		smart.mineInterfaceSpeed()
+		smart.mineQueueDepth()
+		smart.minePowerOnSeconds()
+		smart.mineRotationRate()
+		smart.minePowerCycleCount() // ATA/SATA, NVME, SCSI, SAS
+	}
+	if collectGroups["health"] {
+		smart.mineSmartStatus()
+		smart.minePrefailStatus()
+		smart.mineHealthScore()
+		smart.mineFirmwareUpdateRecommended()
+		smart.mineDeviceSCTStatus()
+		smart.mineDeviceERC()
+		smart.mineATAOfflineDataCollection()
+		smart.mineATASelfTestPollingMinutes()
+		smart.mineUncorrectedErrors()
+		smart.mineLifeRemaining()
+	}
+	if collectGroups["temperature"] {
+		smart.mineTemperatures()
+		smart.mineTemperatureLimits()
+	}
+	if collectGroups["attributes"] {
+		smart.mineDeviceAttribute()
+		smart.mineDeviceAttributeFlags()
+		smart.mineDeviceAttributeStale()
+		smart.mineDeviceAttributesFailingNow()
+		smart.mineDeviceAttributeRawDeltas()
+		smart.mineUDMACRCErrors()
+		smart.mineReallocationEvents()
+		smart.mineDeviceAttributeRawStrings()
+	}
+	if collectGroups["statistics"] {
+		smart.mineDeviceStatistics()
+	}
+	if collectGroups["errorlog"] {
+		smart.mineDeviceErrorLog()
+		smart.mineATALastError()
+		smart.mineDeviceSelfTestLog()
+		smart.mineNvmeSelfTestLog()
+		smart.mineATALogDirectory()
+	}
+
+	if collectGroups["vendor"] {
+		smart.mineVendorLogs()
+	}
+
+	if smart.device.interface_ == "nvme" && collectGroups["nvme"] {
 		smart.mineNvmePercentageUsed()
+		smart.mineNvmePercentageUsedRatio()
 		smart.mineNvmeAvailableSpare()
 		smart.mineNvmeAvailableSpareThreshold()
 		smart.mineNvmeCriticalWarning()
 		smart.mineNvmeMediaErrors()
 		smart.mineNvmeNumErrLogEntries()
+		smart.mineNvmeErrorLogEntries()
+		smart.mineNvmeUnsafeShutdowns()
+		smart.mineNvmeOCPExtendedLog()
+		smart.mineNvmeEnduranceGroups()
+		smart.mineNvmeBadNANDBlocks()
+		smart.mineNvmePCIeLink()
+		if *smartctlNvmeNamespaceMetrics {
+			smart.mineNvmeNamespaces()
+		}
 		smart.mineNvmeBytesRead()
 		smart.mineNvmeBytesWritten()
 	}
 	// SCSI, SAS
-	if smart.device.interface_ == "scsi" {
+	if smart.device.interface_ == "scsi" && collectGroups["scsi"] {
+		smart.mineSCSIDeviceInfo()
 		smart.mineSCSIGrownDefectList()
+		smart.mineSCSIDefectLists()
+		smart.mineSCSIBackgroundScan()
+		smart.mineSASPhyErrorCounters()
 		smart.mineSCSIErrorCounterLog()
 		smart.mineSCSIBytesRead()
 		smart.mineSCSIBytesWritten()
 	}
 }
 
+func (smart *SMARTctl) mineStandby(mode string) {
+	state := 1.0
+	if mode == "SLEEP" {
+		state = 2
+	}
+	smart.mineExitStatus()
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceState,
+		prometheus.GaugeValue,
+		state,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineNoSMARTSupport(noSMART bool) {
+	value := 0.0
+	if noSMART {
+		value = 1
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceNoSMARTSupport,
+		prometheus.GaugeValue,
+		value,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineSMARTSupport() {
+	// NVMe devices have no SMART support flags, their health log is mandatory
+	for desc, path := range map[*prometheus.Desc]string{
+		metricDeviceSMARTAvailable: "smart_support.available",
+		metricDeviceSMARTEnabled:   "smart_support.enabled",
+	} {
+		supported := smart.json.Get(path)
+		if !supported.Exists() {
+			continue
+		}
+		value := 0.0
+		if supported.Bool() {
+			value = 1
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			desc,
+			prometheus.GaugeValue,
+			value,
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+	}
+}
+
 func (smart *SMARTctl) mineExitStatus() {
 	smart.ch <- prometheus.MustNewConstMetric(
 		metricDeviceExitStatus,
 		prometheus.GaugeValue,
 		smart.json.Get("smartctl.exit_status").Float(),
 		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineDataAge() {
+	if smart.cache.LastCollect.IsZero() {
+		return
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceDataAge,
+		prometheus.GaugeValue,
+		time.Since(smart.cache.LastCollect).Seconds(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineClockSkew() {
+	if smart.cache.LastCollect.IsZero() || !smart.json.Get("local_time.time_t").Exists() {
+		return
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceClockSkew,
+		prometheus.GaugeValue,
+		smart.cache.ClockSkew.Seconds(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineDataRestored() {
+	if smart.cache.LastCollect.IsZero() {
+		return
+	}
+	restored := 0.0
+	if smart.cache.Restored {
+		restored = 1
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceDataRestored,
+		prometheus.GaugeValue,
+		restored,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineJSONSize() {
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceJSONBytes,
+		prometheus.GaugeValue,
+		float64(len(smart.json.Raw)),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
 	)
 }
 
@@ -149,6 +575,7 @@ func (smart *SMARTctl) mineDevice() {
 		prometheus.GaugeValue,
 		1,
 		smart.device.device,
+		smart.device.alias,
 		smart.device.interface_,
 		smart.device.protocol,
 		smart.device.family,
@@ -167,6 +594,159 @@ func (smart *SMARTctl) mineDevice() {
 	)
 }
 
+func (smart *SMARTctl) mineDevicePath() {
+	if !*smartctlOmitDevicePath {
+		return
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDevicePath,
+		prometheus.GaugeValue,
+		1,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		smart.device.path,
+	)
+}
+
+// deviceNumbers holds the kernel major and minor number of the devices by
+// path, as read by the last scan
+var deviceNumbers sync.Map
+
+// refreshDeviceNumbers reads the major and minor number of the devices. Those
+// behind a RAID controller share its device node and are skipped.
+func refreshDeviceNumbers(devices []Device) {
+	deviceNumbers.Range(func(key, value any) bool {
+		deviceNumbers.Delete(key)
+		return true
+	})
+	for _, device := range devices {
+		if deviceController(device.Type) != "" {
+			continue
+		}
+		if major, minor, ok := blockDeviceNumber(device.Name); ok {
+			deviceNumbers.Store(device.Name, [2]uint32{major, minor})
+		}
+	}
+}
+
+func (smart *SMARTctl) mineDeviceNumber() {
+	number, ok := deviceNumbers.Load(smart.json.Get("device.name").String())
+	if !ok {
+		return
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceNumber,
+		prometheus.GaugeValue,
+		1,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		strconv.FormatUint(uint64(number.([2]uint32)[0]), 10),
+		strconv.FormatUint(uint64(number.([2]uint32)[1]), 10),
+	)
+}
+
+// raidControllerTypes lists the smartctl device types addressing disks
+// behind a RAID controller
+var raidControllerTypes = []string{"3ware", "aacraid", "areca", "cciss", "hpt", "megaraid"}
+
+// deviceController returns the RAID controller type of a device type, e.g.
+// megaraid for sat+megaraid,0
+func deviceController(deviceType string) string {
+	for _, part := range strings.Split(deviceType, "+") {
+		name, _, _ := strings.Cut(part, ",")
+		if slices.Contains(raidControllerTypes, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+func (smart *SMARTctl) mineDeviceController() {
+	// The device path addresses the controller for disks behind it
+	controllerType := deviceController(smart.json.Get("device.type").String())
+	controller := ""
+	behind := 0.0
+	if controllerType != "" {
+		controller = smart.json.Get("device.name").String()
+		behind = 1
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceBehindController,
+		prometheus.GaugeValue,
+		behind,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		controller,
+		controllerType,
+	)
+}
+
+func (smart *SMARTctl) mineATAVersion() {
+	ataVersion := smart.json.Get("ata_version.string")
+	sataVersion := smart.json.Get("sata_version.string")
+	if !ataVersion.Exists() && !sataVersion.Exists() {
+		return
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricATAVersion,
+		prometheus.GaugeValue,
+		1,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		strings.TrimSpace(ataVersion.String()),
+		strings.TrimSpace(sataVersion.String()),
+	)
+}
+
+func (smart *SMARTctl) mineCacheState() {
+	for desc, path := range map[*prometheus.Desc]string{
+		metricDeviceWriteCacheEnabled:    "write_cache.enabled",
+		metricDeviceReadLookaheadEnabled: "read_lookahead.enabled",
+	} {
+		enabled := smart.json.Get(path)
+		if !enabled.Exists() {
+			continue
+		}
+		value := 0.0
+		if enabled.Bool() {
+			value = 1
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			desc,
+			prometheus.GaugeValue,
+			value,
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+	}
+}
+
+func (smart *SMARTctl) mineATAPowerManagement() {
+	// smartctl reports no level for disabled or unsupported features
+	for desc, path := range map[*prometheus.Desc]string{
+		metricDeviceAPMLevel: "ata_apm.level",
+		metricDeviceAAMLevel: "ata_aam.level",
+	} {
+		value := smart.json.Get(path)
+		if !value.Exists() {
+			continue
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			desc,
+			prometheus.GaugeValue,
+			value.Float(),
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+	}
+}
+
 func (smart *SMARTctl) mineCapacity() {
 	// The user_capacity exists only when NVMe have single namespace. Otherwise,
 	// for NVMe devices with multiple namespaces, when device name used without
@@ -176,12 +756,16 @@ func (smart *SMARTctl) mineCapacity() {
 		prometheus.GaugeValue,
 		smart.json.Get("user_capacity.blocks").Float(),
 		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
 	)
 	smart.ch <- prometheus.MustNewConstMetric(
 		metricDeviceCapacityBytes,
 		prometheus.GaugeValue,
 		smart.json.Get("user_capacity.bytes").Float(),
 		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
 	)
 	nvme_total_capacity := smart.json.Get("nvme_total_capacity")
 	if nvme_total_capacity.Exists() {
@@ -190,6 +774,8 @@ func (smart *SMARTctl) mineCapacity() {
 			prometheus.GaugeValue,
 			nvme_total_capacity.Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 	}
 }
@@ -201,42 +787,150 @@ func (smart *SMARTctl) mineBlockSize() {
 			prometheus.GaugeValue,
 			smart.json.Get(fmt.Sprintf("%s_block_size", blockType)).Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 			blockType,
 		)
 	}
 }
 
-func (smart *SMARTctl) mineInterfaceSpeed() {
-	// TODO: Support scsi_sas_port_[01].phy_N.negotiated_logical_link_rate
-	iSpeed := smart.json.Get("interface_speed")
-	if iSpeed.Exists() {
-		for _, speedType := range []string{"max", "current"} {
-			tSpeed := iSpeed.Get(speedType)
-			if tSpeed.Exists() {
-				smart.ch <- prometheus.MustNewConstMetric(
-					metricDeviceInterfaceSpeed,
-					prometheus.GaugeValue,
-					tSpeed.Get("units_per_second").Float()*tSpeed.Get("bits_per_unit").Float(),
-					smart.device.device,
-					speedType,
-				)
-			}
-		}
+func (smart *SMARTctl) mineSectorEmulation() {
+	logical := smart.json.Get("logical_block_size")
+	physical := smart.json.Get("physical_block_size")
+	if !logical.Exists() || !physical.Exists() {
+		return
 	}
-}
-
-func (smart *SMARTctl) mineDeviceAttribute() {
-	for _, attribute := range smart.json.Get("ata_smart_attributes.table").Array() {
-		name := strings.TrimSpace(attribute.Get("name").String())
-		flagsShort := strings.TrimSpace(attribute.Get("flags.string").String())
-		flagsLong := smart.mineLongFlags(attribute.Get("flags"), []string{
-			"prefailure",
-			"updated_online",
-			"performance",
-			"error_rate",
-			"event_count",
-			"auto_keep",
-		})
+	var mode string
+	switch {
+	case logical.Int() == 512 && physical.Int() == 512:
+		mode = "512n"
+	case logical.Int() == 512 && physical.Int() == 4096:
+		mode = "512e"
+	case logical.Int() == 4096 && physical.Int() == 4096:
+		mode = "4kn"
+	default:
+		return
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceSectorEmulation,
+		prometheus.GaugeValue,
+		1,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		mode,
+	)
+}
+
+func (smart *SMARTctl) mineTrim() {
+	// ATA reports TRIM, SCSI logical block provisioning management (UNMAP)
+	supported := smart.json.Get("trim.supported")
+	if !supported.Exists() {
+		supported = smart.json.Get("scsi_lb_provisioning.management_enabled.value")
+	}
+	if !supported.Exists() {
+		return
+	}
+	value := 0.0
+	if supported.Bool() {
+		value = 1
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceTrimSupported,
+		prometheus.GaugeValue,
+		value,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineZoned() {
+	capabilities := smart.json.Get("zoned_device.capabilities")
+	if !capabilities.Exists() {
+		return
+	}
+	model := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(capabilities.String())), " ", "_")
+	if model == "device_managed" {
+		model = "drive_managed"
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceZoned,
+		prometheus.GaugeValue,
+		1,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		model,
+	)
+}
+
+func (smart *SMARTctl) mineInterfaceSpeed() {
+	// TODO: Support scsi_sas_port_[01].phy_N.negotiated_logical_link_rate
+	iSpeed := smart.json.Get("interface_speed")
+	if iSpeed.Exists() {
+		for _, speedType := range []string{"max", "current"} {
+			tSpeed := iSpeed.Get(speedType)
+			if tSpeed.Exists() {
+				smart.ch <- prometheus.MustNewConstMetric(
+					metricDeviceInterfaceSpeed,
+					prometheus.GaugeValue,
+					tSpeed.Get("units_per_second").Float()*tSpeed.Get("bits_per_unit").Float(),
+					smart.device.device,
+					smart.device.alias,
+					smart.device.interface_,
+					speedType,
+				)
+			}
+		}
+	}
+}
+
+func (smart *SMARTctl) mineQueueDepth() {
+	// smartctl does not report the queue depth in use, read it from sysfs on
+	// Linux. Devices behind RAID controllers have no block device to read.
+	path, err := filepath.EvalSymlinks(smart.json.Get("device.name").String())
+	if err != nil {
+		return
+	}
+	content, err := os.ReadFile(filepath.Join("/sys/block", filepath.Base(path), "device", "queue_depth"))
+	if err != nil {
+		return
+	}
+	depth, err := strconv.ParseFloat(strings.TrimSpace(string(content)), 64)
+	if err != nil {
+		return
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceQueueDepth,
+		prometheus.GaugeValue,
+		depth,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+	if smart.json.Get("device.protocol").String() == "ATA" {
+		// libata sets the queue depth to 1 if NCQ is unsupported or disabled
+		ncq := 0.0
+		if depth > 1 {
+			ncq = 1
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricDeviceNCQEnabled,
+			prometheus.GaugeValue,
+			ncq,
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+	}
+}
+
+func (smart *SMARTctl) mineDeviceAttribute() {
+	for _, attribute := range smart.json.Get("ata_smart_attributes.table").Array() {
+		name := strings.TrimSpace(attribute.Get("name").String())
+		flagsShort := strings.TrimSpace(attribute.Get("flags.string").String())
+		flagsLong := smart.mineLongFlags(attribute.Get("flags"), ataAttributeFlags)
 		id := attribute.Get("id").String()
 		for key, path := range map[string]string{
 			"value":  "value",
@@ -249,6 +943,8 @@ func (smart *SMARTctl) mineDeviceAttribute() {
 				prometheus.GaugeValue,
 				attribute.Get(path).Float(),
 				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
 				name,
 				flagsShort,
 				flagsLong,
@@ -259,15 +955,216 @@ func (smart *SMARTctl) mineDeviceAttribute() {
 	}
 }
 
+func (smart *SMARTctl) mineDeviceAttributeFlags() {
+	for _, attribute := range smart.json.Get("ata_smart_attributes.table").Array() {
+		name := strings.TrimSpace(attribute.Get("name").String())
+		id := attribute.Get("id").String()
+		for _, flag := range ataAttributeFlags {
+			value := 0.0
+			if attribute.Get("flags." + flag).Bool() {
+				value = 1
+			}
+			smart.ch <- prometheus.MustNewConstMetric(
+				metricDeviceAttributeFlags,
+				prometheus.GaugeValue,
+				value,
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+				name,
+				id,
+				flag,
+			)
+		}
+	}
+}
+
+func (smart *SMARTctl) mineDeviceAttributesFailingNow() {
+	attributes := smart.json.Get("ata_smart_attributes.table")
+	if !attributes.Exists() {
+		return
+	}
+	failing := 0
+	for _, attribute := range attributes.Array() {
+		// A threshold of 0 means the attribute never fails, as smartctl has it
+		thresh := attribute.Get("thresh").Int()
+		if thresh > 0 && attribute.Get("value").Int() <= thresh {
+			failing++
+		}
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceAttributesFailingNow,
+		prometheus.GaugeValue,
+		float64(failing),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineDeviceAttributeStale() {
+	// Attributes without the updated online flag only change when offline
+	// data collection runs. Drives report no time of the last collection,
+	// so the values are considered frozen if it never ran or only runs when
+	// started manually.
+	status := smart.json.Get("ata_smart_data.offline_data_collection.status.value")
+	if !status.Exists() {
+		return
+	}
+	offlineStale := status.Int()&0x7f == 0 || status.Int()&0x80 == 0
+	for _, attribute := range smart.json.Get("ata_smart_attributes.table").Array() {
+		stale := 0.0
+		if offlineStale && !attribute.Get("flags.updated_online").Bool() {
+			stale = 1
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricDeviceAttributeStale,
+			prometheus.GaugeValue,
+			stale,
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+			strings.TrimSpace(attribute.Get("name").String()),
+			attribute.Get("id").String(),
+		)
+	}
+}
+
+func (smart *SMARTctl) mineDeviceAttributeRawDeltas() {
+	if !smart.cache.Previous.Exists() {
+		return
+	}
+	previous := map[int64]gjson.Result{}
+	for _, attribute := range smart.cache.Previous.Get("ata_smart_attributes.table").Array() {
+		previous[attribute.Get("id").Int()] = attribute
+	}
+	for _, attribute := range smart.json.Get("ata_smart_attributes.table").Array() {
+		id := attribute.Get("id").Int()
+		last, ok := previous[id]
+		if !ok || !slices.Contains(ataDeltaAttributes, id) {
+			continue
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricDeviceAttributeRawDelta,
+			prometheus.GaugeValue,
+			attribute.Get("raw.value").Float()-last.Get("raw.value").Float(),
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+			strings.TrimSpace(attribute.Get("name").String()),
+			attribute.Get("id").String(),
+		)
+	}
+}
+
+func (smart *SMARTctl) mineDeviceAttributeRawStrings() {
+	if len(rawStringAttributes) == 0 {
+		return
+	}
+	for _, attribute := range smart.json.Get("ata_smart_attributes.table").Array() {
+		raw := attribute.Get("raw.string")
+		if !raw.Exists() || !slices.Contains(rawStringAttributes, attribute.Get("id").Int()) {
+			continue
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricDeviceAttributeRawString,
+			prometheus.GaugeValue,
+			1,
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+			strings.TrimSpace(attribute.Get("name").String()),
+			attribute.Get("id").String(),
+			strings.TrimSpace(raw.String()),
+		)
+	}
+}
+
+func (smart *SMARTctl) mineUDMACRCErrors() {
+	// Attribute 199 is cumulative over the drive lifetime, so report it as a counter.
+	for _, attribute := range smart.json.Get("ata_smart_attributes.table").Array() {
+		if attribute.Get("id").Int() == 199 {
+			smart.ch <- prometheus.MustNewConstMetric(
+				metricDeviceUDMACRCErrors,
+				prometheus.CounterValue,
+				attribute.Get("raw.value").Float(),
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+			)
+			return
+		}
+	}
+}
+
+func (smart *SMARTctl) mineReallocationEvents() {
+	// Attribute 196 counts remap operations over the drive lifetime, a single
+	// one may reallocate many sectors
+	for _, attribute := range smart.json.Get("ata_smart_attributes.table").Array() {
+		if attribute.Get("id").Int() == 196 {
+			smart.ch <- prometheus.MustNewConstMetric(
+				metricDeviceReallocationEvents,
+				prometheus.CounterValue,
+				attribute.Get("raw.value").Float(),
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+			)
+			return
+		}
+	}
+}
+
+// attributeUnits lists ATA attributes some vendors report in other units
+// than smartctl assumes for drives missing from its drive database, with the
+// seconds per raw unit
+var attributeUnits = []struct {
+	model   *regexp.Regexp
+	id      int64
+	name    string
+	seconds float64
+}{
+	// Maxtor DiamondMax 16 and Plus 9 count power on minutes
+	{regexp.MustCompile(`^Maxtor (4R|6Y)[0-9]{3}[LMP]0`), 9, "Power_On_Hours", 60},
+	// Fujitsu MHS20xxAT count power on seconds
+	{regexp.MustCompile(`^FUJITSU MHS20[0-9]{2}AT`), 9, "Power_On_Hours", 1},
+	// Samsung SpinPoint V40 and V60 count power on half minutes
+	{regexp.MustCompile(`^SAMSUNG SV[0-9]{4}[DH]$`), 9, "Power_On_Hours", 30},
+}
+
+// scaledAttribute returns the raw value of the attribute in seconds, if the
+// drive is known to use odd units smartctl did not account for. Attributes
+// smartctl already renamed, e.g. to Power_On_Minutes, are left alone.
+func scaledAttribute(json gjson.Result, id int64) (float64, bool) {
+	model := strings.TrimSpace(json.Get("model_name").String())
+	for _, unit := range attributeUnits {
+		if unit.id != id || !unit.model.MatchString(model) {
+			continue
+		}
+		for _, attribute := range json.Get("ata_smart_attributes.table").Array() {
+			if attribute.Get("id").Int() == id && attribute.Get("name").String() == unit.name {
+				return attribute.Get("raw.value").Float() * unit.seconds, true
+			}
+		}
+	}
+	return 0, false
+}
+
 func (smart *SMARTctl) minePowerOnSeconds() {
 	pot := smart.json.Get("power_on_time")
 	// If the power_on_time is NOT present, do not report as 0.
 	if pot.Exists() {
+		seconds, ok := scaledAttribute(smart.json, 9)
+		if !ok {
+			seconds = GetFloatIfExists(pot, "hours", 0)*60*60 + GetFloatIfExists(pot, "minutes", 0)*60
+		}
 		smart.ch <- prometheus.MustNewConstMetric(
 			metricDevicePowerOnSeconds,
 			prometheus.CounterValue,
-			GetFloatIfExists(pot, "hours", 0)*60*60+GetFloatIfExists(pot, "minutes", 0)*60,
+			seconds,
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 	}
 }
@@ -282,6 +1179,8 @@ func (smart *SMARTctl) mineRotationRate() {
 			prometheus.GaugeValue,
 			rRate,
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 	}
 }
@@ -296,6 +1195,8 @@ func (smart *SMARTctl) mineTemperatures() {
 				prometheus.GaugeValue,
 				value.Float(),
 				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
 				key.String(),
 			)
 			return true
@@ -303,96 +1204,565 @@ func (smart *SMARTctl) mineTemperatures() {
 	}
 }
 
-func (smart *SMARTctl) minePowerCycleCount() {
-	// ATA & NVME
-	powerCycleCount := smart.json.Get("power_cycle_count")
-	if powerCycleCount.Exists() {
-		smart.ch <- prometheus.MustNewConstMetric(
-			metricDevicePowerCycleCount,
-			prometheus.CounterValue,
-			powerCycleCount.Float(),
-			smart.device.device,
-		)
+// temperatureLimits lists where the temperature limits of every kind are
+// found, for NVMe, ATA SCT and SCSI devices in that order
+var temperatureLimits = []struct {
+	kind  string
+	paths []string
+}{
+	{"warning", []string{
+		"temperature.op_limit_max",
+		"ata_sct_status.temperature.op_limit_max",
+	}},
+	{"critical", []string{
+		"temperature.critical_limit_max",
+		"temperature.limit_max",
+		"ata_sct_status.temperature.limit_max",
+		"temperature.drive_trip",
+	}},
+}
+
+func (smart *SMARTctl) mineTemperatureLimits() {
+	for _, limit := range temperatureLimits {
+		for _, path := range limit.paths {
+			value := smart.json.Get(path)
+			if !value.Exists() {
+				continue
+			}
+			smart.ch <- prometheus.MustNewConstMetric(
+				metricDeviceTemperatureLimit,
+				prometheus.GaugeValue,
+				value.Float(),
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+				limit.kind,
+			)
+			break
+		}
+	}
+}
+
+func (smart *SMARTctl) minePowerCycleCount() {
+	// ATA & NVME
+	powerCycleCount := smart.json.Get("power_cycle_count")
+	if powerCycleCount.Exists() {
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricDevicePowerCycleCount,
+			prometheus.CounterValue,
+			powerCycleCount.Float(),
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+		return
+	}
+
+	// SCSI
+	powerCycleCount = smart.json.Get("scsi_start_stop_cycle_counter.accumulated_start_stop_cycles")
+	if powerCycleCount.Exists() {
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricDevicePowerCycleCount,
+			prometheus.CounterValue,
+			powerCycleCount.Float(),
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+		return
+	}
+}
+
+func (smart *SMARTctl) mineDeviceSCTStatus() {
+	status := smart.json.Get("ata_sct_status")
+	if status.Exists() {
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricDeviceState,
+			prometheus.GaugeValue,
+			status.Get("device_state").Float(),
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+	}
+}
+
+func (smart *SMARTctl) mineATAOfflineDataCollection() {
+	status := smart.json.Get("ata_smart_data.offline_data_collection.status.value")
+	if status.Exists() {
+		// Bit 7 flags automatic offline data collection, the rest is the status
+		value := status.Int()
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricATAOfflineDataCollectionStatus,
+			prometheus.GaugeValue,
+			float64(value&0x7f),
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+		auto := 0.0
+		if value&0x80 != 0 {
+			auto = 1
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricATAOfflineDataCollectionAutoEnabled,
+			prometheus.GaugeValue,
+			auto,
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+	}
+}
+
+func (smart *SMARTctl) mineATASelfTestPollingMinutes() {
+	// Only the tests the drive supports are reported, e.g. short, extended
+	// and conveyance
+	for test, minutes := range smart.json.Get("ata_smart_data.self_test.polling_minutes").Map() {
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricATASelfTestPollingMinutes,
+			prometheus.GaugeValue,
+			minutes.Float(),
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+			test,
+		)
+	}
+}
+
+func (smart *SMARTctl) mineNvmePercentageUsed() {
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDevicePercentageUsed,
+		prometheus.CounterValue,
+		smart.json.Get("nvme_smart_health_information_log.percentage_used").Float(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineNvmePercentageUsedRatio() {
+	// The percentage used may reach 255 on drives worn past their rated
+	// endurance, it is not capped at 1
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDevicePercentageUsedRatio,
+		prometheus.GaugeValue,
+		smart.json.Get("nvme_smart_health_information_log.percentage_used").Float()/100,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineNvmeAvailableSpare() {
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceAvailableSpare,
+		prometheus.CounterValue,
+		smart.json.Get("nvme_smart_health_information_log.available_spare").Float(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineNvmeAvailableSpareThreshold() {
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceAvailableSpareThreshold,
+		prometheus.CounterValue,
+		smart.json.Get("nvme_smart_health_information_log.available_spare_threshold").Float(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineNvmeCriticalWarning() {
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceCriticalWarning,
+		prometheus.CounterValue,
+		smart.json.Get("nvme_smart_health_information_log.critical_warning").Float(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineNvmeMediaErrors() {
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceMediaErrors,
+		prometheus.CounterValue,
+		smart.json.Get("nvme_smart_health_information_log.media_errors").Float(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineNvmeNumErrLogEntries() {
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceNumErrLogEntries,
+		prometheus.CounterValue,
+		smart.json.Get("nvme_smart_health_information_log.num_err_log_entries").Float(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+// monotonicCounter keeps counters reported by devices monotonic when the
+// device resets them, e.g. with a firmware update, by carrying over the last
+// value seen before the reset
+type monotonicCounter struct {
+	mutex  sync.Mutex
+	last   map[string]float64
+	offset map[string]float64
+}
+
+func newMonotonicCounter() *monotonicCounter {
+	return &monotonicCounter{last: map[string]float64{}, offset: map[string]float64{}}
+}
+
+// value returns the monotonic value for the reported value of the key
+func (c *monotonicCounter) value(key string, reported float64) float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if last, ok := c.last[key]; ok && reported < last {
+		c.offset[key] += last
+	}
+	c.last[key] = reported
+	return c.offset[key] + reported
+}
+
+// monotonicKey returns the key of the counters of the polled device, which
+// includes the type as a device may be configured with several
+func monotonicKey(json gjson.Result) string {
+	return json.Get("device.name").String() + ";" + json.Get("device.type").String()
+}
+
+var nvmeErrorLogEntries = newMonotonicCounter()
+
+// ataDeviceStatistic returns the named statistic of the ATA device
+// statistics log
+func ataDeviceStatistic(json gjson.Result, name string) gjson.Result {
+	for _, page := range json.Get("ata_device_statistics.pages").Array() {
+		for _, statistic := range page.Get("table").Array() {
+			if strings.TrimSpace(statistic.Get("name").String()) == name {
+				return statistic.Get("value")
+			}
+		}
+	}
+	return gjson.Result{}
+}
+
+// deviceWear returns the used endurance in percent and the bytes written of
+// SSDs reporting them
+func deviceWear(json gjson.Result) (float64, float64, bool) {
+	if health := json.Get("nvme_smart_health_information_log"); health.Get("percentage_used").Exists() {
+		// NVMe data units are thousands of 512 byte blocks
+		return health.Get("percentage_used").Float(), health.Get("data_units_written").Float() * 512000, true
+	}
+	used := ataDeviceStatistic(json, "Percentage Used Endurance Indicator")
+	if !used.Exists() {
+		return 0, 0, false
+	}
+	written := ataDeviceStatistic(json, "Logical Sectors Written").Float() * json.Get("logical_block_size").Float()
+	return used.Float(), written, true
+}
+
+// lifePoint is the first observed wear of a device, the baseline of its
+// write rate
+type lifePoint struct {
+	identity  string
+	collected time.Time
+	written   float64
+}
+
+var lifeBaselines sync.Map
+
+func (smart *SMARTctl) mineLifeRemaining() {
+	used, written, ok := deviceWear(smart.json)
+	if !ok {
+		return
+	}
+	// The percentage used may exceed 100 once the rated endurance is reached
+	remaining := math.Max(0, 100-used) / 100
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceLifeRemaining,
+		prometheus.GaugeValue,
+		remaining,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+
+	// The end of life is projected from the write rate between the first
+	// and the current poll, the endurance being derived from the bytes
+	// written per percent used
+	collected := smart.cache.LastCollect
+	if collected.IsZero() || used <= 0 || written <= 0 {
+		return
+	}
+	// A disk replaced under the same name starts a new baseline
+	point := lifePoint{identity: deviceIdentity(smart.json), collected: collected, written: written}
+	value, _ := lifeBaselines.LoadOrStore(monotonicKey(smart.json), point)
+	baseline := value.(lifePoint)
+	if baseline.identity != point.identity {
+		lifeBaselines.Store(monotonicKey(smart.json), point)
+		return
+	}
+	elapsed := collected.Sub(baseline.collected).Seconds()
+	if elapsed <= 0 || written <= baseline.written {
+		return
+	}
+	rate := (written - baseline.written) / elapsed
+	endurance := written * 100 / used
+	endOfLife := float64(collected.Unix()) + math.Max(0, endurance-written)/rate
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceEstimatedEndOfLife,
+		prometheus.GaugeValue,
+		endOfLife,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+var uncorrectedErrors = newMonotonicCounter()
+
+// uncorrectedErrorCount returns the errors the device reported as not
+// correctable, whatever its protocol: the Reported_Uncorrect attribute of ATA
+// devices, the media errors of NVMe devices and the uncorrected read, write
+// and verify errors of SCSI devices
+func uncorrectedErrorCount(json gjson.Result) (float64, bool) {
+	for _, attribute := range json.Get("ata_smart_attributes.table").Array() {
+		if attribute.Get("id").Int() == 187 {
+			return attribute.Get("raw.value").Float(), true
+		}
+	}
+	if mediaErrors := json.Get("nvme_smart_health_information_log.media_errors"); mediaErrors.Exists() {
+		return mediaErrors.Float(), true
+	}
+	counterLog := json.Get("scsi_error_counter_log")
+	if !counterLog.Exists() {
+		return 0, false
+	}
+	count := 0.0
+	for _, operation := range []string{"read", "write", "verify"} {
+		count += counterLog.Get(operation + ".total_uncorrected_errors").Float()
+	}
+	return count, true
+}
+
+func (smart *SMARTctl) mineUncorrectedErrors() {
+	count, ok := uncorrectedErrorCount(smart.json)
+	if !ok {
+		return
+	}
+	// SCSI error counter logs can be reset from the host
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceUncorrectedErrors,
+		prometheus.CounterValue,
+		uncorrectedErrors.value(monotonicKey(smart.json), count),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
+func (smart *SMARTctl) mineNvmeErrorLogEntries() {
+	entries := smart.json.Get("nvme_smart_health_information_log.num_err_log_entries")
+	if !entries.Exists() {
 		return
 	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricNvmeErrorLogEntries,
+		prometheus.CounterValue,
+		nvmeErrorLogEntries.value(monotonicKey(smart.json), entries.Float()),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
 
-	// SCSI
-	powerCycleCount = smart.json.Get("scsi_start_stop_cycle_counter.accumulated_start_stop_cycles")
-	if powerCycleCount.Exists() {
+func (smart *SMARTctl) mineNvmeUnsafeShutdowns() {
+	unsafeShutdowns := smart.json.Get("nvme_smart_health_information_log.unsafe_shutdowns")
+	if unsafeShutdowns.Exists() {
 		smart.ch <- prometheus.MustNewConstMetric(
-			metricDevicePowerCycleCount,
+			metricNvmeUnsafeShutdowns,
 			prometheus.CounterValue,
-			powerCycleCount.Float(),
+			unsafeShutdowns.Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
-		return
 	}
 }
 
-func (smart *SMARTctl) mineDeviceSCTStatus() {
-	status := smart.json.Get("ata_sct_status")
-	if status.Exists() {
+func (smart *SMARTctl) mineNvmeNamespaces() {
+	for _, namespace := range smart.json.Get("nvme_namespaces").Array() {
+		utilization := namespace.Get("utilization.bytes")
+		if !utilization.Exists() {
+			continue
+		}
 		smart.ch <- prometheus.MustNewConstMetric(
-			metricDeviceState,
+			metricNvmeNamespaceUtilizationBytes,
 			prometheus.GaugeValue,
-			status.Get("device_state").Float(),
+			utilization.Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+			namespace.Get("id").String(),
 		)
 	}
 }
 
-func (smart *SMARTctl) mineNvmePercentageUsed() {
-	smart.ch <- prometheus.MustNewConstMetric(
-		metricDevicePercentageUsed,
-		prometheus.CounterValue,
-		smart.json.Get("nvme_smart_health_information_log.percentage_used").Float(),
-		smart.device.device,
-	)
+// nvmeOCPLog returns the OCP (Open Compute Project) SMART extended log,
+// preferring the standardized section over the vendor specific one
+func (smart *SMARTctl) nvmeOCPLog() gjson.Result {
+	var ocpLog gjson.Result
+	for _, key := range []string{"nvme_ocp_smart_extended_log", "nvme_vendor_specific"} {
+		if ocpLog = smart.json.Get(key); ocpLog.Exists() {
+			break
+		}
+	}
+	return ocpLog
 }
 
-func (smart *SMARTctl) mineNvmeAvailableSpare() {
-	smart.ch <- prometheus.MustNewConstMetric(
-		metricDeviceAvailableSpare,
-		prometheus.CounterValue,
-		smart.json.Get("nvme_smart_health_information_log.available_spare").Float(),
-		smart.device.device,
-	)
+func (smart *SMARTctl) mineNvmeOCPExtendedLog() {
+	// The OCP SMART extended log is only provided by some drives and
+	// smartctl builds, so skip it when absent.
+	ocpLog := smart.nvmeOCPLog()
+	if !ocpLog.Exists() {
+		return
+	}
+	// The bad NAND block counts are exported by mineNvmeBadNANDBlocks
+	for desc, field := range map[*prometheus.Desc]string{
+		metricNvmeOCPPhysicalMediaUnitsWritten: "physical_media_units_written",
+		metricNvmeOCPPhysicalMediaUnitsRead:    "physical_media_units_read",
+		metricNvmeOCPXORRecoveries:             "xor_recovery_count",
+	} {
+		if value := ocpLog.Get(field); value.Exists() {
+			smart.ch <- prometheus.MustNewConstMetric(
+				desc,
+				prometheus.CounterValue,
+				value.Float(),
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+			)
+		}
+	}
 }
 
-func (smart *SMARTctl) mineNvmeAvailableSpareThreshold() {
-	smart.ch <- prometheus.MustNewConstMetric(
-		metricDeviceAvailableSpareThreshold,
-		prometheus.CounterValue,
-		smart.json.Get("nvme_smart_health_information_log.available_spare_threshold").Float(),
-		smart.device.device,
-	)
+func (smart *SMARTctl) mineNvmeEnduranceGroups() {
+	// Only drives with several endurance groups report the log, the
+	// controller level metrics cover the others
+	for _, group := range smart.json.Get("nvme_endurance_group_information_log").Array() {
+		id := group.Get("id")
+		if !id.Exists() {
+			continue
+		}
+		for desc, field := range map[*prometheus.Desc]string{
+			metricNvmeEnduranceGroupPercentageUsed:          "percentage_used",
+			metricNvmeEnduranceGroupAvailableSpare:          "available_spare",
+			metricNvmeEnduranceGroupAvailableSpareThreshold: "available_spare_threshold",
+		} {
+			value := group.Get(field)
+			if !value.Exists() {
+				continue
+			}
+			smart.ch <- prometheus.MustNewConstMetric(
+				desc,
+				prometheus.GaugeValue,
+				value.Float()/100,
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+				id.String(),
+			)
+		}
+	}
 }
 
-func (smart *SMARTctl) mineNvmeCriticalWarning() {
-	smart.ch <- prometheus.MustNewConstMetric(
-		metricDeviceCriticalWarning,
-		prometheus.CounterValue,
-		smart.json.Get("nvme_smart_health_information_log.critical_warning").Float(),
-		smart.device.device,
-	)
+// nvmeControllerName returns the controller name, e.g. nvme0, of an NVMe
+// controller or namespace device path
+func nvmeControllerName(path string) string {
+	if match := nvmeNamespaceRegexp.FindStringSubmatch(path); match != nil {
+		path = match[1]
+	}
+	if !nvmeControllerRegexp.MatchString(path) {
+		return ""
+	}
+	return filepath.Base(path)
 }
 
-func (smart *SMARTctl) mineNvmeMediaErrors() {
-	smart.ch <- prometheus.MustNewConstMetric(
-		metricDeviceMediaErrors,
-		prometheus.CounterValue,
-		smart.json.Get("nvme_smart_health_information_log.media_errors").Float(),
-		smart.device.device,
-	)
+func (smart *SMARTctl) mineNvmePCIeLink() {
+	// smartctl does not report the PCIe link, read it from sysfs on Linux
+	controller := nvmeControllerName(smart.json.Get("device.name").String())
+	if controller == "" {
+		return
+	}
+	sysfs := filepath.Join("/sys/class/nvme", controller, "device")
+	for _, link := range []string{"current", "max"} {
+		// The speed is reported like "8.0 GT/s PCIe"
+		if speed, err := os.ReadFile(filepath.Join(sysfs, link+"_link_speed")); err == nil {
+			if fields := strings.Fields(string(speed)); len(fields) > 0 {
+				if value, err := strconv.ParseFloat(fields[0], 64); err == nil {
+					smart.ch <- prometheus.MustNewConstMetric(
+						metricNvmePCIeLinkSpeed,
+						prometheus.GaugeValue,
+						value,
+						smart.device.device,
+						smart.device.alias,
+						smart.device.interface_,
+						link,
+					)
+				}
+			}
+		}
+		if width, err := os.ReadFile(filepath.Join(sysfs, link+"_link_width")); err == nil {
+			if value, err := strconv.ParseFloat(strings.TrimSpace(string(width)), 64); err == nil {
+				smart.ch <- prometheus.MustNewConstMetric(
+					metricNvmePCIeLinkWidth,
+					prometheus.GaugeValue,
+					value,
+					smart.device.device,
+					smart.device.alias,
+					smart.device.interface_,
+					link,
+				)
+			}
+		}
+	}
 }
 
-func (smart *SMARTctl) mineNvmeNumErrLogEntries() {
-	smart.ch <- prometheus.MustNewConstMetric(
-		metricDeviceNumErrLogEntries,
-		prometheus.CounterValue,
-		smart.json.Get("nvme_smart_health_information_log.num_err_log_entries").Float(),
-		smart.device.device,
-	)
+func (smart *SMARTctl) mineNvmeBadNANDBlocks() {
+	// The standard SMART / Health Information log has no bad block counts,
+	// they are only reported by drives providing the OCP extended log.
+	ocpLog := smart.nvmeOCPLog()
+	for _, kind := range []string{"user", "system"} {
+		blocks := ocpLog.Get(fmt.Sprintf("bad_%s_nand_blocks", kind))
+		if !blocks.IsObject() {
+			continue
+		}
+		for _, valueType := range []string{"raw", "normalized"} {
+			value := blocks.Get(valueType)
+			if !value.Exists() {
+				continue
+			}
+			smart.ch <- prometheus.MustNewConstMetric(
+				metricNvmeBadNANDBlocks,
+				prometheus.GaugeValue,
+				value.Float(),
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+				kind,
+				valueType,
+			)
+		}
+	}
 }
 
 // https://nvmexpress.org/wp-content/uploads/NVM-Express-NVM-Command-Set-Specification-1.0d-2023.12.28-Ratified.pdf
@@ -431,6 +1801,8 @@ func (smart *SMARTctl) mineNvmeBytesRead() {
 		// The underlying data_units_written,data_units_read are 128-bit integers
 		data_units_read.Float()*1000.0*512.0,
 		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
 	)
 }
 
@@ -447,6 +1819,8 @@ func (smart *SMARTctl) mineNvmeBytesWritten() {
 		// The underlying data_units_written,data_units_read are 128-bit integers
 		data_units_written.Float()*1000.0*512.0,
 		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
 	)
 }
 
@@ -461,6 +1835,8 @@ func (smart *SMARTctl) mineSCSIBytesRead() {
 			// that is not the responsibility of the exporter or smartctl
 			SCSIHealth.Get("read.gigabytes_processed").Float()*1e9,
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 	}
 }
@@ -476,19 +1852,102 @@ func (smart *SMARTctl) mineSCSIBytesWritten() {
 			// that is not the responsibility of the exporter or smartctl
 			SCSIHealth.Get("write.gigabytes_processed").Float()*1e9,
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 	}
 }
 
+// firmwareIssueMessages match the drive database warnings smartctl reports
+// for firmware with known issues
+var firmwareIssueMessages = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)firmware update .*(available|recommended|required)`),
+	regexp.MustCompile(`(?i)firmware (bug|version) .*(may|can|known to)`),
+}
+
+// knownBadFirmware lists firmware versions with known issues smartctl does
+// not warn about in every version of its drive database
+var knownBadFirmware = []struct {
+	model    *regexp.Regexp
+	firmware *regexp.Regexp
+}{
+	// Crucial m4 become unresponsive after 5184 hours, fixed in 0309
+	{regexp.MustCompile(`^M4-CT[0-9]{3}M4SSD[123]$`), regexp.MustCompile(`^000[129]$`)},
+	// Seagate Barracuda 7200.11 may lock up in the busy state on boot
+	{regexp.MustCompile(`^ST3(160813|320613|500320|500620|500820|640323|750330|1000333)AS$`), regexp.MustCompile(`^(SD1[5-9]|AD14)$`)},
+}
+
+// firmwareUpdateRecommended reports whether the firmware of the device has
+// known issues, as far as the rules cover it
+func firmwareUpdateRecommended(json gjson.Result) bool {
+	for _, message := range json.Get("smartctl.messages").Array() {
+		for _, pattern := range firmwareIssueMessages {
+			if pattern.MatchString(message.Get("string").String()) {
+				return true
+			}
+		}
+	}
+	model := strings.TrimSpace(json.Get("model_name").String())
+	firmware := strings.TrimSpace(json.Get("firmware_version").String())
+	for _, rule := range knownBadFirmware {
+		if rule.model.MatchString(model) && rule.firmware.MatchString(firmware) {
+			return true
+		}
+	}
+	return false
+}
+
+func (smart *SMARTctl) mineFirmwareUpdateRecommended() {
+	// Only drives looked up in the drive database, i.e. ATA ones, are covered
+	if !smart.json.Get("in_smartctl_database").Exists() {
+		return
+	}
+	value := 0.0
+	if firmwareUpdateRecommended(smart.json) {
+		value = 1
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceFirmwareUpdateRecommended,
+		prometheus.GaugeValue,
+		value,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+}
+
 func (smart *SMARTctl) mineSmartStatus() {
 	smart.ch <- prometheus.MustNewConstMetric(
 		metricDeviceSmartStatus,
 		prometheus.GaugeValue,
 		smart.json.Get("smart_status.passed").Float(),
 		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
 	)
 }
 
+func (smart *SMARTctl) minePrefailStatus() {
+	exitStatus := smart.json.Get("smartctl.exit_status").Int()
+	for desc, bit := range map[*prometheus.Desc]int64{
+		metricDevicePrefail:     exitPrefailBelowThreshold,
+		metricDevicePastPrefail: exitPastBelowThreshold,
+	} {
+		value := 0.0
+		if exitStatus&bit != 0 {
+			value = 1
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			desc,
+			prometheus.GaugeValue,
+			value,
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+	}
+}
+
 func (smart *SMARTctl) mineDeviceStatistics() {
 	for _, page := range smart.json.Get("ata_device_statistics.pages").Array() {
 		table := strings.TrimSpace(page.Get("name").String())
@@ -503,6 +1962,8 @@ func (smart *SMARTctl) mineDeviceStatistics() {
 				prometheus.GaugeValue,
 				statistic.Get("value").Float(),
 				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
 				table,
 				strings.TrimSpace(statistic.Get("name").String()),
 				strings.TrimSpace(statistic.Get("flags.string").String()),
@@ -522,6 +1983,8 @@ func (smart *SMARTctl) mineDeviceStatistics() {
 			prometheus.GaugeValue,
 			statistic.Get("value").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 			"SATA PHY Event Counters",
 			strings.TrimSpace(statistic.Get("name").String()),
 			"V---",
@@ -548,11 +2011,56 @@ func (smart *SMARTctl) mineDeviceErrorLog() {
 			prometheus.GaugeValue,
 			status.Get("count").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 			logType,
 		)
 	}
 }
 
+// ataErrorTypeRegexp extracts the error type from the error log description,
+// e.g. "UNC" from "Error: UNC at LBA = 0x00a1b2c3 = 10597059"
+var ataErrorTypeRegexp = regexp.MustCompile(`^Error: (.+?)(?: [0-9]+ sectors?)?(?: at LBA .*)?$`)
+
+func (smart *SMARTctl) mineATALastError() {
+	// The extended log, read with the xerror group, holds more entries and
+	// wins over the summary log
+	table := smart.json.Get("ata_smart_error_log.extended.table")
+	if !table.Exists() {
+		table = smart.json.Get("ata_smart_error_log.summary.table")
+	}
+	var last gjson.Result
+	for _, entry := range table.Array() {
+		if !last.Exists() || entry.Get("error_number").Int() > last.Get("error_number").Int() {
+			last = entry
+		}
+	}
+	if !last.Get("lifetime_hours").Exists() {
+		return
+	}
+	errorType := "unknown"
+	if match := ataErrorTypeRegexp.FindStringSubmatch(last.Get("error_description").String()); match != nil {
+		errorType = match[1]
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricATALastErrorLifetimeHours,
+		prometheus.GaugeValue,
+		last.Get("lifetime_hours").Float(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		errorType,
+	)
+}
+
+// Number of entries the self-test logs hold before the oldest are
+// overwritten. The extended ATA log holds 19 entries per sector.
+const (
+	ataSelfTestLogEntries          = 21
+	ataExtSelfTestLogSectorEntries = 19
+	nvmeSelfTestLogEntries         = 20
+)
+
 func (smart *SMARTctl) mineDeviceSelfTestLog() {
 	for logType, status := range smart.json.Get("ata_smart_self_test_log").Map() {
 		smart.ch <- prometheus.MustNewConstMetric(
@@ -560,18 +2068,98 @@ func (smart *SMARTctl) mineDeviceSelfTestLog() {
 			prometheus.GaugeValue,
 			status.Get("count").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 			logType,
 		)
+		capacity := int64(ataSelfTestLogEntries)
+		if logType == "extended" {
+			capacity = status.Get("sectors").Int() * ataExtSelfTestLogSectorEntries
+		}
+		smart.mineSelfTestLogWrapped(logType, status.Get("count").Int(), capacity)
 		smart.ch <- prometheus.MustNewConstMetric(
 			metricDeviceSelfTestLogErrorCount,
 			prometheus.GaugeValue,
 			status.Get("error_count_total").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 			logType,
 		)
 	}
 }
 
+func (smart *SMARTctl) mineNvmeSelfTestLog() {
+	selfTestLog := smart.json.Get("nvme_self_test_log")
+	if !selfTestLog.Exists() {
+		return
+	}
+	count := int64(len(selfTestLog.Get("table").Array()))
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceSelfTestLogCount,
+		prometheus.GaugeValue,
+		float64(count),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		"nvme",
+	)
+	smart.mineSelfTestLogWrapped("nvme", count, nvmeSelfTestLogEntries)
+}
+
+// mineSelfTestLogWrapped reports whether the self-test log is full, so older
+// entries are being overwritten. Logs of unknown capacity are skipped.
+func (smart *SMARTctl) mineSelfTestLogWrapped(logType string, count int64, capacity int64) {
+	if capacity <= 0 {
+		return
+	}
+	wrapped := 0.0
+	if count >= capacity {
+		wrapped = 1
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricDeviceSelfTestLogWrapped,
+		prometheus.GaugeValue,
+		wrapped,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		logType,
+	)
+}
+
+func (smart *SMARTctl) mineATALogDirectory() {
+	if len(logDirectoryAddresses) == 0 {
+		return
+	}
+	for _, entry := range smart.json.Get("ata_log_directory.table").Array() {
+		address := entry.Get("address").Uint()
+		if !slices.Contains(logDirectoryAddresses, address) {
+			continue
+		}
+		for logType, path := range map[string]string{
+			"gp":    "gp_sectors",
+			"smart": "smart_sectors",
+		} {
+			sectors := entry.Get(path)
+			if !sectors.Exists() {
+				continue
+			}
+			smart.ch <- prometheus.MustNewConstMetric(
+				metricATALogDirectorySectors,
+				prometheus.GaugeValue,
+				sectors.Float(),
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+				fmt.Sprintf("0x%02x", address),
+				strings.TrimSpace(entry.Get("name").String()),
+				logType,
+			)
+		}
+	}
+}
+
 func (smart *SMARTctl) mineDeviceERC() {
 	for ercType, status := range smart.json.Get("ata_sct_erc").Map() {
 		smart.ch <- prometheus.MustNewConstMetric(
@@ -579,11 +2167,30 @@ func (smart *SMARTctl) mineDeviceERC() {
 			prometheus.GaugeValue,
 			status.Get("deciseconds").Float()/10.0,
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 			ercType,
 		)
 	}
 }
 
+func (smart *SMARTctl) mineSCSIDeviceInfo() {
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricSCSIDeviceInfo,
+		prometheus.GaugeValue,
+		1,
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+		strings.TrimSpace(smart.json.Get("scsi_vendor").String()),
+		strings.TrimSpace(smart.json.Get("scsi_product").String()),
+		strings.TrimSpace(smart.json.Get("scsi_revision").String()),
+		smart.json.Get("scsi_version").String(),
+		smart.json.Get("logical_unit_id").String(),
+		smart.json.Get("scsi_transport_protocol.name").String(),
+	)
+}
+
 func (smart *SMARTctl) mineSCSIGrownDefectList() {
 	scsi_grown_defect_list := smart.json.Get("scsi_grown_defect_list")
 	if scsi_grown_defect_list.Exists() {
@@ -592,6 +2199,112 @@ func (smart *SMARTctl) mineSCSIGrownDefectList() {
 			prometheus.GaugeValue,
 			scsi_grown_defect_list.Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+		)
+	}
+}
+
+func (smart *SMARTctl) mineSCSIDefectLists() {
+	// Many drives report the grown defect list only
+	for source, path := range map[string]string{
+		"primary": "scsi_primary_defect_list",
+		"grown":   "scsi_grown_defect_list",
+	} {
+		count := smart.json.Get(path)
+		if !count.Exists() {
+			continue
+		}
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricSCSIDefectListCount,
+			prometheus.GaugeValue,
+			count.Float(),
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
+			source,
+		)
+	}
+}
+
+// sasPhyErrorCounters maps the SAS phy log counters to their metrics
+var sasPhyErrorCounters = []struct {
+	key  string
+	desc *prometheus.Desc
+}{
+	{"invalid_dword_count", metricSASPhyInvalidDword},
+	{"running_disparity_error_count", metricSASPhyRunningDisparityErrors},
+	{"loss_of_dword_synchronization_count", metricSASPhyLossOfDwordSync},
+	{"phy_reset_problem_count", metricSASPhyResetProblems},
+}
+
+func (smart *SMARTctl) mineSASPhyErrorCounters() {
+	// The phys are reported per port as scsi_sas_port_N.phy_M
+	for key, port := range smart.json.Map() {
+		portNumber, found := strings.CutPrefix(key, "scsi_sas_port_")
+		if !found {
+			continue
+		}
+		for key, phy := range port.Map() {
+			phyNumber, found := strings.CutPrefix(key, "phy_")
+			if !found {
+				continue
+			}
+			for _, counter := range sasPhyErrorCounters {
+				value := phy.Get(counter.key)
+				if !value.Exists() {
+					continue
+				}
+				smart.ch <- prometheus.MustNewConstMetric(
+					counter.desc,
+					prometheus.CounterValue,
+					value.Float(),
+					smart.device.device,
+					smart.device.alias,
+					smart.device.interface_,
+					portNumber,
+					phyNumber,
+				)
+			}
+		}
+	}
+}
+
+func (smart *SMARTctl) mineSCSIBackgroundScan() {
+	status := smart.json.Get("scsi_background_scan.status")
+	if !status.Exists() {
+		return
+	}
+	smart.ch <- prometheus.MustNewConstMetric(
+		metricSCSIBackgroundScanStatus,
+		prometheus.GaugeValue,
+		status.Get("value").Float(),
+		smart.device.device,
+		smart.device.alias,
+		smart.device.interface_,
+	)
+	// Progress is reported as a percentage string by some smartctl versions
+	if progress := status.Get("scan_progress"); progress.Exists() {
+		value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(progress.String()), "%")), 64)
+		if err == nil {
+			smart.ch <- prometheus.MustNewConstMetric(
+				metricSCSIBackgroundScanProgress,
+				prometheus.GaugeValue,
+				value,
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+			)
+		}
+	}
+	if scans := status.Get("number_scans_performed"); scans.Exists() {
+		smart.ch <- prometheus.MustNewConstMetric(
+			metricSCSIBackgroundScans,
+			prometheus.CounterValue,
+			scans.Float(),
+			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 	}
 }
@@ -604,48 +2317,64 @@ func (smart *SMARTctl) mineSCSIErrorCounterLog() {
 			prometheus.GaugeValue,
 			SCSIHealth.Get("read.errors_corrected_by_rereads_rewrites").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 		smart.ch <- prometheus.MustNewConstMetric(
 			metricReadErrorsCorrectedByEccFast,
 			prometheus.GaugeValue,
 			SCSIHealth.Get("read.errors_corrected_by_eccfast").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 		smart.ch <- prometheus.MustNewConstMetric(
 			metricReadErrorsCorrectedByEccDelayed,
 			prometheus.GaugeValue,
 			SCSIHealth.Get("read.errors_corrected_by_eccdelayed").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 		smart.ch <- prometheus.MustNewConstMetric(
 			metricReadTotalUncorrectedErrors,
 			prometheus.GaugeValue,
 			SCSIHealth.Get("read.total_uncorrected_errors").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 		smart.ch <- prometheus.MustNewConstMetric(
 			metricWriteErrorsCorrectedByRereadsRewrites,
 			prometheus.GaugeValue,
 			SCSIHealth.Get("write.errors_corrected_by_rereads_rewrites").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 		smart.ch <- prometheus.MustNewConstMetric(
 			metricWriteErrorsCorrectedByEccFast,
 			prometheus.GaugeValue,
 			SCSIHealth.Get("write.errors_corrected_by_eccfast").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 		smart.ch <- prometheus.MustNewConstMetric(
 			metricWriteErrorsCorrectedByEccDelayed,
 			prometheus.GaugeValue,
 			SCSIHealth.Get("write.errors_corrected_by_eccdelayed").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 		smart.ch <- prometheus.MustNewConstMetric(
 			metricWriteTotalUncorrectedErrors,
 			prometheus.GaugeValue,
 			SCSIHealth.Get("write.total_uncorrected_errors").Float(),
 			smart.device.device,
+			smart.device.alias,
+			smart.device.interface_,
 		)
 		// TODO: Should we also export the verify category?
 	}
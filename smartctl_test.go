@@ -0,0 +1,315 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/tidwall/gjson"
+)
+
+func TestMonotonicCounterReset(t *testing.T) {
+	counter := newMonotonicCounter()
+	for i, step := range []struct {
+		reported float64
+		want     float64
+	}{
+		{10, 10},
+		{12, 12},
+		// Repeated scrapes of the same data do not change the value
+		{12, 12},
+		// The count was reset, e.g. by a firmware update
+		{2, 14},
+		{2, 14},
+		{5, 17},
+		{1, 18},
+	} {
+		if got := counter.value("/dev/nvme0", step.reported); got != step.want {
+			t.Errorf("step %d: value(%v) = %v, want %v", i, step.reported, got, step.want)
+		}
+	}
+	if got := counter.value("/dev/nvme1", 3); got != 3 {
+		t.Errorf("other device: got %v, want 3", got)
+	}
+	// A device polled with two types keeps a counter per type
+	sat := monotonicKey(gjson.Parse(`{"device":{"name":"/dev/sda","type":"sat"}}`))
+	scsi := monotonicKey(gjson.Parse(`{"device":{"name":"/dev/sda","type":"scsi"}}`))
+	counter.value(sat, 100)
+	if got := counter.value(scsi, 5); got != 5 {
+		t.Errorf("other type: got %v, want 5", got)
+	}
+	if got := counter.value(sat, 100); got != 100 {
+		t.Errorf("first type after other type: got %v, want 100", got)
+	}
+}
+
+func benchmarkJSON(b *testing.B) gjson.Result {
+	data, err := os.ReadFile("testdata/SAMSUNG_MZQLB1T9HAJR-00007_19.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return gjson.ParseBytes(data)
+}
+
+func BenchmarkNewSMARTctl(b *testing.B) {
+	json := benchmarkJSON(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewSMARTctl(log.NewNopLogger(), json, nil)
+	}
+}
+
+func BenchmarkNewSMARTctlFromCache(b *testing.B) {
+	json := benchmarkJSON(b)
+	labels := newSMARTDevice(json)
+	cache := JSONCache{JSON: json, Labels: &labels}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewSMARTctlFromCache(log.NewNopLogger(), cache, nil)
+	}
+}
+
+func TestDeviceController(t *testing.T) {
+	for deviceType, want := range map[string]string{
+		"sat+megaraid,0": "megaraid",
+		"megaraid,12":    "megaraid",
+		"areca,3/1":      "areca",
+		"cciss,0":        "cciss",
+		"sat":            "",
+		"nvme":           "",
+	} {
+		if got := deviceController(deviceType); got != want {
+			t.Errorf("deviceController(%q) = %q, want %q", deviceType, got, want)
+		}
+	}
+}
+
+func TestUncorrectedErrorCount(t *testing.T) {
+	tests := []struct {
+		json  string
+		count float64
+		ok    bool
+	}{
+		{`{"ata_smart_attributes": {"table": [{"id": 5, "raw": {"value": 8}}, {"id": 187, "raw": {"value": 3}}]}}`, 3, true},
+		{`{"ata_smart_attributes": {"table": [{"id": 5, "raw": {"value": 8}}]}}`, 0, false},
+		{`{"nvme_smart_health_information_log": {"media_errors": 2}}`, 2, true},
+		{`{"scsi_error_counter_log": {"read": {"total_uncorrected_errors": 1}, "write": {"total_uncorrected_errors": 4}, "verify": {"total_uncorrected_errors": 2}}}`, 7, true},
+	}
+	for _, test := range tests {
+		count, ok := uncorrectedErrorCount(gjson.Parse(test.json))
+		if count != test.count || ok != test.ok {
+			t.Errorf("%s: got %v, %v, want %v, %v", test.json, count, ok, test.count, test.ok)
+		}
+	}
+}
+
+func TestLifeRemaining(t *testing.T) {
+	device := `"device": {"name": "/dev/nvme9", "type": "nvme"}`
+	poll := func(used, units int, collected time.Time) map[string]float64 {
+		json := gjson.Parse(fmt.Sprintf(`{%s, "nvme_smart_health_information_log": {"percentage_used": %d, "data_units_written": %d}}`, device, used, units))
+		ch := make(chan prometheus.Metric, 10)
+		smart := SMARTctl{json: json, ch: ch, cache: JSONCache{LastCollect: collected}}
+		smart.mineLifeRemaining()
+		close(ch)
+		values := map[string]float64{}
+		for metric := range ch {
+			var m dto.Metric
+			if err := metric.Write(&m); err != nil {
+				t.Fatal(err)
+			}
+			values[metric.Desc().String()] = m.GetGauge().GetValue()
+		}
+		return values
+	}
+	defer lifeBaselines.Delete("/dev/nvme9;nvme")
+
+	start := time.Unix(1000000000, 0)
+	first := poll(10, 1000000, start)
+	if len(first) != 1 || first[metricDeviceLifeRemaining.String()] != 0.9 {
+		t.Fatalf("first poll: %v", first)
+	}
+	// 10% used after 2000000 units rates the endurance at 20000000 units,
+	// the remaining 18000000 take 18000 seconds at 1000 units a second
+	second := poll(10, 2000000, start.Add(1000*time.Second))
+	if eol := second[metricDeviceEstimatedEndOfLife.String()]; eol != float64(start.Unix()+1000+18000) {
+		t.Errorf("end of life = %v", eol)
+	}
+
+	// A replacement disk under the same name has no write rate yet
+	device = `"device": {"name": "/dev/nvme9", "type": "nvme"}, "serial_number": "S2"`
+	if replaced := poll(1, 3000000, start.Add(2000*time.Second)); len(replaced) != 1 {
+		t.Errorf("replaced disk: %v", replaced)
+	}
+	syncDeviceState([]Device{{Name: "/dev/nvme0", Type: "nvme"}})
+	if _, ok := lifeBaselines.Load("/dev/nvme9;nvme"); ok {
+		t.Error("baseline of a removed device kept")
+	}
+}
+
+func TestNvmeEnduranceGroups(t *testing.T) {
+	json := gjson.Parse(`{"nvme_endurance_group_information_log": [
+		{"id": 1, "available_spare": 100, "available_spare_threshold": 10, "percentage_used": 3},
+		{"id": 2, "available_spare": 40, "available_spare_threshold": 10, "percentage_used": 120}
+	]}`)
+	ch := make(chan prometheus.Metric, 10)
+	smart := SMARTctl{json: json, ch: ch}
+	smart.mineNvmeEnduranceGroups()
+	close(ch)
+	used := map[string]float64{}
+	count := 0
+	for metric := range ch {
+		count++
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if metric.Desc() != metricNvmeEnduranceGroupPercentageUsed {
+			continue
+		}
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "endurance_group" {
+				used[label.GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	if count != 6 || used["1"] != 0.03 || used["2"] != 1.2 {
+		t.Errorf("got %d metrics, percentage used %v", count, used)
+	}
+
+	ch = make(chan prometheus.Metric, 10)
+	smart = SMARTctl{json: gjson.Parse(`{"nvme_smart_health_information_log": {"percentage_used": 3}}`), ch: ch}
+	smart.mineNvmeEnduranceGroups()
+	close(ch)
+	if len(ch) != 0 {
+		t.Errorf("got %d metrics without endurance groups", len(ch))
+	}
+}
+
+func TestScaledAttribute(t *testing.T) {
+	tests := []struct {
+		json    string
+		seconds float64
+		ok      bool
+	}{
+		{`{"model_name": "Maxtor 4R080L0", "ata_smart_attributes": {"table": [{"id": 9, "name": "Power_On_Hours", "raw": {"value": 120}}]}}`, 7200, true},
+		// smartctl knows the drive and converted the minutes already
+		{`{"model_name": "Maxtor 4R080L0", "ata_smart_attributes": {"table": [{"id": 9, "name": "Power_On_Minutes", "raw": {"value": 120}}]}}`, 0, false},
+		{`{"model_name": "WDC WD20EFRX-68EUZN0", "ata_smart_attributes": {"table": [{"id": 9, "name": "Power_On_Hours", "raw": {"value": 120}}]}}`, 0, false},
+	}
+	for _, test := range tests {
+		seconds, ok := scaledAttribute(gjson.Parse(test.json), 9)
+		if seconds != test.seconds || ok != test.ok {
+			t.Errorf("%s: got %v, %v, want %v, %v", test.json, seconds, ok, test.seconds, test.ok)
+		}
+	}
+}
+
+func TestHealthScore(t *testing.T) {
+	tests := []struct {
+		json  string
+		score float64
+		ok    bool
+	}{
+		{`{"ata_smart_attributes": {"table": [{"id": 5, "value": 100, "thresh": 10}, {"id": 197, "value": 100, "thresh": 0}]}}`, 100, true},
+		// Halfway from the nominal value of 100 to the threshold
+		{`{"ata_smart_attributes": {"table": [{"id": 5, "value": 55, "thresh": 10}, {"id": 197, "value": 100, "thresh": 0}]}}`, 50, true},
+		// Attributes starting at 200 with a threshold above 100
+		{`{"ata_smart_attributes": {"table": [{"id": 5, "value": 170, "thresh": 140}]}}`, 50, true},
+		{`{"ata_smart_attributes": {"table": [{"id": 5, "value": 5, "thresh": 10}]}}`, 0, true},
+		{`{"ata_smart_attributes": {"table": [{"id": 9, "value": 5, "thresh": 10}]}}`, 100, false},
+		// A threshold at the highest nominal value leaves nothing to score
+		{`{"ata_smart_attributes": {"table": [{"id": 5, "value": 253, "thresh": 253}]}}`, 100, false},
+		{`{"ata_smart_attributes": {"table": [{"id": 5, "value": 253, "thresh": 254}, {"id": 197, "value": 55, "thresh": 10}]}}`, 50, true},
+	}
+	for _, test := range tests {
+		score, ok := healthScore(gjson.Parse(test.json), []int64{5, 187, 197, 198})
+		if score != test.score || ok != test.ok {
+			t.Errorf("%s: got %v, %v, want %v, %v", test.json, score, ok, test.score, test.ok)
+		}
+	}
+}
+
+func TestFirmwareUpdateRecommended(t *testing.T) {
+	tests := []struct {
+		json        string
+		recommended bool
+	}{
+		{`{"model_name": "M4-CT256M4SSD2", "firmware_version": "0009"}`, true},
+		{`{"model_name": "M4-CT256M4SSD2", "firmware_version": "0309"}`, false},
+		{`{"model_name": "ST3500418AS", "smartctl": {"messages": [{"string": "A firmware update for this drive may be available, see the following Seagate web pages:", "severity": "warning"}]}}`, true},
+		{`{"model_name": "WDC WD20EFRX-68EUZN0", "firmware_version": "82.00A82"}`, false},
+	}
+	for _, test := range tests {
+		if recommended := firmwareUpdateRecommended(gjson.Parse(test.json)); recommended != test.recommended {
+			t.Errorf("%s: got %v, want %v", test.json, recommended, test.recommended)
+		}
+	}
+}
+
+func TestPercentageUsedPastRatedEndurance(t *testing.T) {
+	data, err := os.ReadFile("testdata/KXG60ZNV512G_TOSHIBA_9.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric, 10)
+	smart := NewSMARTctl(log.NewNopLogger(), gjson.ParseBytes(data), ch)
+	smart.mineNvmePercentageUsed()
+	smart.mineNvmePercentageUsedRatio()
+	smart.mineLifeRemaining()
+	defer lifeBaselines.Delete("/dev/nvme2;nvme")
+	close(ch)
+	values := map[*prometheus.Desc]float64{}
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		values[metric.Desc()] = m.GetGauge().GetValue() + m.GetCounter().GetValue()
+	}
+	for desc, want := range map[*prometheus.Desc]float64{
+		metricDevicePercentageUsed:      150,
+		metricDevicePercentageUsedRatio: 1.5,
+		metricDeviceLifeRemaining:       0,
+	} {
+		if got, ok := values[desc]; !ok || got != want {
+			t.Errorf("%s = %v, want %v", desc, got, want)
+		}
+	}
+}
+
+func TestDeviceLabelFallback(t *testing.T) {
+	// Info names the device path is not extracted from keep the label of
+	// the scanned device
+	json := gjson.Parse(`{"device": {"name": "/dev/sdb", "info_name": "/dev/sdb [SAT]", "type": "sat"}}`)
+	if labels := newSMARTDevice(json); labels.device != extractDiskName("/dev/sdb [SAT]") {
+		t.Errorf("device = %q, want %q", labels.device, extractDiskName("/dev/sdb [SAT]"))
+	}
+
+	path := filepath.Join(t.TempDir(), "smartd.WDC_WD40EFRX-WCC7K0000000.ata.state")
+	if err := os.WriteFile(path, []byte("ata-error-count = 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	device := Device{Name: path, Info_Name: "WDC_WD40EFRX-WCC7K0000000", Type: smartdDeviceType}
+	defer jsonCache.Delete(device)
+	if data := readSmartdData(log.NewNopLogger(), device); data.Labels == nil || data.Labels.device != device.Info_Name {
+		t.Errorf("smartd labels = %+v, want device %q", data.Labels, device.Info_Name)
+	}
+}
@@ -58,9 +58,18 @@ func (smart *SMARTctlInfo) mineVersion() {
 		metricSmartctlVersion,
 		prometheus.GaugeValue,
 		1,
-		fmt.Sprintf("%d.%d", jsonVersion[0].Int(), jsonVersion[1].Int()),
-		fmt.Sprintf("%d.%d", smartctlVersion[0].Int(), smartctlVersion[1].Int()),
+		versionString(jsonVersion),
+		versionString(smartctlVersion),
 		smartctlJSON.Get("svn_revision").String(),
 		smartctlJSON.Get("build_info").String(),
 	)
 }
+
+// versionString formats a [major, minor] version, which is missing e.g. from
+// the plaintext output
+func versionString(version []gjson.Result) string {
+	if len(version) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d", version[0].Int(), version[1].Int())
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/tidwall/gjson"
+)
+
+// smartdDeviceType is the device type of devices read from smartd state files
+const smartdDeviceType = "smartd"
+
+// smartdStateRegexp matches the state files smartd writes with -s, named
+// after the model and serial number of the device
+var smartdStateRegexp = regexp.MustCompile(`^smartd\.(.+)\.(ata|nvme)\.state$`)
+
+var smartdAttributeRegexp = regexp.MustCompile(`^ata-smart-attribute\.([0-9]+)\.(id|val|worst|raw)$`)
+
+// scanSmartdStates registers every smartd state file in dir as a device
+func scanSmartdStates(logger log.Logger, dir string, filter deviceFilter) []Device {
+	paths, err := filepath.Glob(filepath.Join(dir, "smartd.*.state"))
+	if err != nil {
+		level.Error(logger).Log("msg", "smartd state discovery failed", "err", err)
+		return nil
+	}
+	var devices []Device
+	for _, path := range paths {
+		match := smartdStateRegexp.FindStringSubmatch(filepath.Base(path))
+		if match == nil {
+			continue
+		}
+		if filter.ignored(match[1]) {
+			level.Info(logger).Log("msg", "Ignoring device", "name", match[1])
+			continue
+		}
+		level.Info(logger).Log("msg", "Found smartd state", "name", match[1], "path", path)
+		devices = append(devices, Device{
+			Name:      path,
+			Info_Name: match[1],
+			Type:      smartdDeviceType,
+		})
+	}
+	return devices
+}
+
+// readSmartdState reads a smartd state file and maps its values to the
+// smartctl json layout. smartd does not store attribute names, and model and
+// serial number are split at the last dash of the file name.
+func readSmartdState(logger log.Logger, device Device) (gjson.Result, bool) {
+	match := smartdStateRegexp.FindStringSubmatch(filepath.Base(device.Name))
+	if match == nil {
+		return gjson.Result{}, false
+	}
+	file, err := os.Open(device.Name)
+	if err != nil {
+		level.Error(logger).Log("msg", "Reading smartd state failed", "path", device.Name, "err", err)
+		return gjson.Result{}, false
+	}
+	defer file.Close()
+
+	deviceType, protocol := "sat", "ATA"
+	if match[2] == "nvme" {
+		deviceType, protocol = "nvme", "NVMe"
+	}
+	model, serial := match[1], ""
+	if i := strings.LastIndex(match[1], "-"); i > 0 {
+		model, serial = match[1][:i], match[1][i+1:]
+	}
+	data := map[string]interface{}{
+		"device": map[string]interface{}{
+			"name":      device.Name,
+			"info_name": match[1],
+			"type":      deviceType,
+			"protocol":  protocol,
+		},
+		"model_name":    strings.ReplaceAll(model, "_", " "),
+		"serial_number": serial,
+		"smartctl":      map[string]interface{}{"exit_status": 0},
+	}
+
+	attributes := map[int]map[string]interface{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		number, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "ata-error-count":
+			data["ata_smart_error_log"] = map[string]interface{}{
+				"summary": map[string]interface{}{"count": number},
+			}
+		case "nvme-err-log-entries":
+			data["nvme_smart_health_information_log"] = map[string]interface{}{
+				"num_err_log_entries": number,
+			}
+		}
+		if attribute := smartdAttributeRegexp.FindStringSubmatch(key); attribute != nil {
+			index, _ := strconv.Atoi(attribute[1])
+			if attributes[index] == nil {
+				attributes[index] = map[string]interface{}{}
+			}
+			switch attribute[2] {
+			case "val":
+				attributes[index]["value"] = number
+			case "raw":
+				attributes[index]["raw"] = map[string]interface{}{"value": number}
+			default:
+				attributes[index][attribute[2]] = number
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		level.Error(logger).Log("msg", "Reading smartd state failed", "path", device.Name, "err", err)
+		return gjson.Result{}, false
+	}
+
+	var indexes []int
+	for index := range attributes {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	var table []interface{}
+	for _, index := range indexes {
+		table = append(table, attributes[index])
+	}
+	if table != nil {
+		data["ata_smart_attributes"] = map[string]interface{}{"table": table}
+	}
+
+	out, err := json.Marshal(data)
+	if err != nil {
+		level.Error(logger).Log("msg", "Converting smartd state failed", "path", device.Name, "err", err)
+		return gjson.Result{}, false
+	}
+	return gjson.ParseBytes(out), true
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/tidwall/gjson"
+)
+
+// cacheSnapshotEntry is a jsonCache entry as persisted to the snapshot file
+type cacheSnapshotEntry struct {
+	Device      Device          `json:"device"`
+	JSON        json.RawMessage `json:"json"`
+	Previous    json.RawMessage `json:"previous,omitempty"`
+	LastCollect time.Time       `json:"last_collect"`
+	ExitStatus  int64           `json:"exit_status"`
+	ClockSkew   time.Duration   `json:"clock_skew"`
+}
+
+// saveCacheSnapshot writes the cached smartctl json of all devices to the
+// file, replacing it atomically
+func saveCacheSnapshot(filename string) error {
+	var entries []cacheSnapshotEntry
+	jsonCache.Range(func(key, value any) bool {
+		cache := value.(JSONCache)
+		if !cache.JSON.Exists() {
+			return true
+		}
+		entry := cacheSnapshotEntry{
+			Device:      key.(Device),
+			JSON:        json.RawMessage(cache.JSON.Raw),
+			LastCollect: cache.LastCollect,
+			ExitStatus:  cache.ExitStatus,
+			ClockSkew:   cache.ClockSkew,
+		}
+		if cache.Previous.Exists() {
+			entry.Previous = json.RawMessage(cache.Previous.Raw)
+		}
+		entries = append(entries, entry)
+		return true
+	})
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// loadCacheSnapshot fills jsonCache from the snapshot file. The entries keep
+// the time they were collected at, so devices are polled again once their
+// interval elapsed, and are marked as restored until then.
+func loadCacheSnapshot(logger log.Logger, filename string) error {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []cacheSnapshotEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		parsed := gjson.ParseBytes(entry.JSON)
+		labels := newSMARTDevice(parsed)
+		cache := JSONCache{
+			JSON:        parsed,
+			LastCollect: entry.LastCollect,
+			ExitStatus:  entry.ExitStatus,
+			Labels:      &labels,
+			ClockSkew:   entry.ClockSkew,
+			Restored:    true,
+		}
+		if entry.Previous != nil {
+			cache.Previous = gjson.ParseBytes(entry.Previous)
+		}
+		jsonCache.Store(entry.Device, cache)
+	}
+	level.Info(logger).Log("msg", "Restored cached smartctl json", "file", filename, "devices", len(entries))
+	return nil
+}
+
+// saveCacheSnapshots writes the cache snapshot every interval
+func saveCacheSnapshots(logger log.Logger, filename string, interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		if err := saveCacheSnapshot(filename); err != nil {
+			level.Error(logger).Log("msg", "Writing the cache snapshot failed", "file", filename, "err", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestCacheSnapshot(t *testing.T) {
+	device := Device{Name: "/dev/sda", Info_Name: "sda", Type: "sat"}
+	collected := time.Now().Add(-time.Minute).Round(0)
+	defer jsonCache.Delete(device)
+	jsonCache.Store(device, JSONCache{
+		JSON:        readFakeSMARTctlFile(log.NewNopLogger(), "testdata/Hitachi_HUA722020ALA330_29.json"),
+		LastCollect: collected,
+		ExitStatus:  4,
+	})
+
+	filename := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := saveCacheSnapshot(filename); err != nil {
+		t.Fatal(err)
+	}
+	jsonCache.Delete(device)
+	if err := loadCacheSnapshot(log.NewNopLogger(), filename); err != nil {
+		t.Fatal(err)
+	}
+
+	value, ok := jsonCache.Load(device)
+	if !ok {
+		t.Fatal("device not restored")
+	}
+	cache := value.(JSONCache)
+	if !cache.Restored || !cache.LastCollect.Equal(collected) || cache.ExitStatus != 4 {
+		t.Errorf("restored entry %+v", cache)
+	}
+	if serial := cache.JSON.Get("serial_number").String(); serial == "" || cache.Labels == nil || cache.Labels.serial != serial {
+		t.Errorf("restored json does not match the labels")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// vendorField maps a field of a vendor specific log to a metric
+type vendorField struct {
+	path      string
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+// vendorLog is a vendor specific log section of the smartctl json
+type vendorLog struct {
+	section string
+	fields  []vendorField
+}
+
+func newVendorField(vendor, path, name, help string, valueType prometheus.ValueType) vendorField {
+	return vendorField{
+		path: path,
+		desc: prometheus.NewDesc(
+			"smartctl_vendor_"+vendor+"_"+name,
+			help,
+			[]string{
+				"device",
+				"alias",
+				"type",
+			},
+			nil,
+		),
+		valueType: valueType,
+	}
+}
+
+// vendorLogs lists the curated fields of known vendor logs
+var vendorLogs = []vendorLog{
+	{
+		// Seagate Field Accessible Reliability Metrics, smartctl --log=farm
+		section: "seagate_farm_log",
+		fields: []vendorField{
+			newVendorField("seagate", "page_1_drive_information.power_on_hours", "power_on_hours", "Seagate FARM power on hours", prometheus.CounterValue),
+			newVendorField("seagate", "page_2_workload_statistics.total_number_of_read_commands", "read_commands_total", "Seagate FARM total number of read commands", prometheus.CounterValue),
+			newVendorField("seagate", "page_2_workload_statistics.total_number_of_write_commands", "write_commands_total", "Seagate FARM total number of write commands", prometheus.CounterValue),
+			newVendorField("seagate", "page_3_error_statistics.number_of_unrecoverable_read_errors", "unrecoverable_read_errors_total", "Seagate FARM number of unrecoverable read errors", prometheus.CounterValue),
+			newVendorField("seagate", "page_3_error_statistics.number_of_unrecoverable_write_errors", "unrecoverable_write_errors_total", "Seagate FARM number of unrecoverable write errors", prometheus.CounterValue),
+			newVendorField("seagate", "page_3_error_statistics.number_of_reallocated_sectors", "reallocated_sectors", "Seagate FARM number of reallocated sectors", prometheus.GaugeValue),
+			newVendorField("seagate", "page_3_error_statistics.number_of_mechanical_start_failures", "mechanical_start_failures_total", "Seagate FARM number of mechanical start failures", prometheus.CounterValue),
+		},
+	},
+}
+
+func (smart *SMARTctl) mineVendorLogs() {
+	for _, vendorLog := range vendorLogs {
+		section := smart.json.Get(vendorLog.section)
+		if !section.Exists() {
+			continue
+		}
+		for _, field := range vendorLog.fields {
+			value := section.Get(field.path)
+			if !value.Exists() {
+				continue
+			}
+			smart.ch <- prometheus.MustNewConstMetric(
+				field.desc,
+				field.valueType,
+				value.Float(),
+				smart.device.device,
+				smart.device.alias,
+				smart.device.interface_,
+			)
+		}
+	}
+}